@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"byeduck.com/serial-monitor/utils"
+)
+
+const DEFAULT_CAPTURE_NAME = "serial_monitor_capture"
+
+var captureFile string
+var captureFormat string
+var captureRotateSizeMb int
+var captureRotateEvery time.Duration
+
+// capture is nil when capturing is off, it's written from the reading and the
+// ui goroutine
+var capture atomic.Pointer[utils.Capture]
+
+func validateCaptureFlags() {
+	var err error
+	captureFormat, err = utils.ParseCaptureFormat(captureFormat, captureFile)
+	utils.Must("parse capture format", err)
+	if captureRotateSizeMb < 0 {
+		log.Fatalln("capture rotate size cannot be negative")
+	}
+	if captureRotateEvery < 0 {
+		log.Fatalln("capture rotate interval cannot be negative")
+	}
+}
+
+// captureFileName returns --capture or the default name used when capturing
+// is turned on with the key
+func captureFileName() string {
+	if captureFile != "" {
+		return captureFile
+	}
+	switch captureFormat {
+	case utils.CAPTURE_CSV:
+		return DEFAULT_CAPTURE_NAME + ".csv"
+	case utils.CAPTURE_JSONL:
+		return DEFAULT_CAPTURE_NAME + ".jsonl"
+	}
+	return DEFAULT_CAPTURE_NAME + ".txt"
+}
+
+func startCapture() error {
+	c, err := utils.OpenCapture(captureFileName(), captureFormat, int64(captureRotateSizeMb)*1024*1024, captureRotateEvery)
+	if err != nil {
+		return err
+	}
+	capture.Store(c)
+	log.Printf("Capturing to %s (%s)\n", c.Path, c.Format)
+	return nil
+}
+
+func stopCapture() {
+	if c := capture.Swap(nil); c != nil {
+		if err := c.Close(); err != nil {
+			log.Printf("Cannot close capture: %v\n", err)
+		}
+		log.Printf("Capture to %s stopped\n", c.Path)
+	}
+}
+
+func startOrStopCapture() {
+	if capture.Load() != nil {
+		stopCapture()
+	} else if err := startCapture(); err != nil {
+		log.Printf("Cannot start capture: %v\n", err)
+	}
+}
+
+func captureMessage(msg *utils.Message) {
+	c := capture.Load()
+	if c == nil {
+		return
+	}
+	if err := c.Write(msg); err != nil {
+		log.Printf("Cannot write capture, stopping: %v\n", err)
+		stopCapture()
+	}
+}
+
+func updateCaptureParagraph() {
+	if !fullScreen {
+		if c := capture.Load(); c != nil {
+			mainGui.CaptureParagraph.Text = fmt.Sprintf("Capture: %s [B]: %d", c.Path, c.Size())
+		} else {
+			mainGui.CaptureParagraph.Text = "Capture: off"
+		}
+	}
+}
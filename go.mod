@@ -5,6 +5,7 @@ go 1.22.0
 require (
 	github.com/gizak/termui/v3 v3.1.0
 	go.bug.st/serial v1.6.1
+	golang.org/x/sys v0.16.0
 )
 
 require (
@@ -12,5 +13,4 @@ require (
 	github.com/mattn/go-runewidth v0.0.2 // indirect
 	github.com/mitchellh/go-wordwrap v0.0.0-20150314170334-ad45545899c7 // indirect
 	github.com/nsf/termbox-go v0.0.0-20190121233118-02980233997d // indirect
-	golang.org/x/sys v0.16.0 // indirect
 )
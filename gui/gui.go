@@ -3,6 +3,7 @@ package gui
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
 	"byeduck.com/serial-monitor/utils"
 	ui "github.com/gizak/termui/v3"
@@ -10,38 +11,71 @@ import (
 )
 
 const (
-	Text string = "TEXT"
-	Plot string = "PLOT"
+	Text  string = "TEXT"
+	Plot  string = "PLOT"
+	Split string = "SPLIT"
 )
 
 const (
 	MAX_MSG_DISPLAY_SIZE = 30
+	INBOX_LIST_TITLE     = "IN messages"
+	STATUS_TITLE         = "Status"
 
-	PARAGRAPH_HEIGHT = 3
-	LIST_ELEM_HEIGHT = 1
+	PARAGRAPH_HEIGHT   = 3
+	STATS_TABLE_HEIGHT = 10
+	LIST_ELEM_HEIGHT   = 1
 )
 
+// series colours along with their names in termui style syntax
+var seriesColors = []ui.Color{ui.ColorYellow, ui.ColorGreen, ui.ColorCyan, ui.ColorMagenta, ui.ColorRed, ui.ColorBlue, ui.ColorWhite}
+var seriesColorNames = []string{"yellow", "green", "cyan", "magenta", "red", "blue", "white"}
+
+var statsHeader = []string{"series", "min", "max", "mean", "std dev", "last", "rate [Hz]"}
+
 func GetAvailableModes() []string {
-	return []string{Text, Plot}
+	return []string{Text, Plot, Split}
+}
+
+// ShowsText tells whether the messages list is a part of the gui in the mode
+func ShowsText(mode string) bool {
+	return mode == Text || mode == Split
+}
+
+// ShowsPlot tells whether the plot is a part of the gui in the mode
+func ShowsPlot(mode string) bool {
+	return mode == Plot || mode == Split
 }
 
 type MainGui struct {
+	StatusBlock                *ui.Block
 	BaudParagraph              *widgets.Paragraph
 	DeviceParagraph            *widgets.Paragraph
+	ConnectionParagraph        *widgets.Paragraph
 	ReadTimeoutParagraph       *widgets.Paragraph
+	FramingParagraph           *widgets.Paragraph
 	LogsEnabledParagraph       *widgets.Paragraph
 	TimestampsEnabledParagraph *widgets.Paragraph
 	HexModeParagraph           *widgets.Paragraph
+	EncodingParagraph          *widgets.Paragraph
+	FilterParagraph            *widgets.Paragraph
 	WrittenDataParagraph       *widgets.Paragraph
 	ReadDataParagraph          *widgets.Paragraph
+	CaptureParagraph           *widgets.Paragraph
 	PauseParagraph             *widgets.Paragraph
+	LocalEchoParagraph         *widgets.Paragraph
+	SendEolParagraph           *widgets.Paragraph
+	HexInputParagraph          *widgets.Paragraph
 	FollowModeParagraph        *widgets.Paragraph
 	InboxList                  *widgets.List
-	InboxPlot                  *widgets.Plot
+	InboxPlot                  *SeriesPlot
+	PlotLegendParagraph        *widgets.Paragraph
+	StatsTable                 *widgets.Table
 	InputParagraph             *widgets.Paragraph
 }
 
-func NewMainGui(mode string, fullScreen bool) *MainGui {
+// NewMainGui creates widgets for the mode. In SPLIT mode splitRatio is the part
+// of the height taken by the messages list, the rest goes to the plot.
+func NewMainGui(mode string, fullScreen bool, splitRatio float64) *MainGui {
 	availableWidth, availableHeight := ui.TerminalDimensions()
 	mainStartX := 0
 	var mainEndX int
@@ -59,100 +93,167 @@ func NewMainGui(mode string, fullScreen bool) *MainGui {
 		}
 	} else if mode == Plot {
 		mainEndY = int(float32(availableHeight) * 0.5)
+	} else if mode == Split {
+		// leave room for the legend and the input
+		mainEndY = availableHeight - 3*PARAGRAPH_HEIGHT - 1
 	}
 
 	var followModeParagraph *widgets.Paragraph
 	var baudParagraph *widgets.Paragraph
 	var deviceParagraph *widgets.Paragraph
+	var connectionParagraph *widgets.Paragraph
 	var readTimeoutParagraph *widgets.Paragraph
+	var framingParagraph *widgets.Paragraph
 	var logsEnabledParagraph *widgets.Paragraph
 	var timestampsEnabledParagraph *widgets.Paragraph
 	var hexModeParagraph *widgets.Paragraph
+	var encodingParagraph *widgets.Paragraph
+	var filterParagraph *widgets.Paragraph
 	var writtenDataParagraph *widgets.Paragraph
 	var readDataParagraph *widgets.Paragraph
+	var captureParagraph *widgets.Paragraph
 	var pauseParagraph *widgets.Paragraph
+	var localEchoParagraph *widgets.Paragraph
+	var sendEolParagraph *widgets.Paragraph
+	var hexInputParagraph *widgets.Paragraph
 
-	configCount := 0
-	const configHeight = 1
+	var statusBlock *ui.Block
 	if !fullScreen {
 		configWidth := int(float32(availableWidth)*0.25) - 1
 		configStartX := mainEndX + 1
 		configEndX := configStartX + configWidth
-		baudParagraph = widgets.NewParagraph()
-		baudParagraph.SetRect(configStartX, (configCount * PARAGRAPH_HEIGHT), configEndX, ((configCount + configHeight) * PARAGRAPH_HEIGHT))
-		configCount++
-		deviceParagraph = widgets.NewParagraph()
-		deviceParagraph.SetRect(configStartX, (configCount * PARAGRAPH_HEIGHT), configEndX, ((configCount + configHeight) * PARAGRAPH_HEIGHT))
-		configCount++
-		readTimeoutParagraph = widgets.NewParagraph()
-		readTimeoutParagraph.SetRect(configStartX, (configCount * PARAGRAPH_HEIGHT), configEndX, ((configCount + configHeight) * PARAGRAPH_HEIGHT))
-		configCount++
-		logsEnabledParagraph = widgets.NewParagraph()
-		logsEnabledParagraph.SetRect(configStartX, (configCount * PARAGRAPH_HEIGHT), configEndX, ((configCount + configHeight) * PARAGRAPH_HEIGHT))
-		configCount++
-		writtenDataParagraph = widgets.NewParagraph()
-		writtenDataParagraph.SetRect(configStartX, (configCount * PARAGRAPH_HEIGHT), configEndX, ((configCount + configHeight) * PARAGRAPH_HEIGHT))
-		configCount++
-		readDataParagraph = widgets.NewParagraph()
-		readDataParagraph.SetRect(configStartX, (configCount * PARAGRAPH_HEIGHT), configEndX, ((configCount + configHeight) * PARAGRAPH_HEIGHT))
-		configCount++
-		pauseParagraph = widgets.NewParagraph()
-		pauseParagraph.SetRect(configStartX, (configCount * PARAGRAPH_HEIGHT), configEndX, ((configCount + configHeight) * PARAGRAPH_HEIGHT))
-		configCount++
-
-		if mode == Text {
-			hexModeParagraph = widgets.NewParagraph()
-			hexModeParagraph.SetRect(configStartX, (configCount * PARAGRAPH_HEIGHT), configEndX, ((configCount + configHeight) * PARAGRAPH_HEIGHT))
-			configCount++
-			timestampsEnabledParagraph = widgets.NewParagraph()
-			timestampsEnabledParagraph.SetRect(configStartX, (configCount * PARAGRAPH_HEIGHT), configEndX, ((configCount + configHeight) * PARAGRAPH_HEIGHT))
-			configCount++
-			followModeParagraph = widgets.NewParagraph()
-			followModeParagraph.SetRect(configStartX, (configCount * PARAGRAPH_HEIGHT), configEndX, ((configCount + configHeight) * PARAGRAPH_HEIGHT))
+		var statusLines []*widgets.Paragraph
+		newStatusLine := func() *widgets.Paragraph {
+			line := newStatusLineParagraph()
+			statusLines = append(statusLines, line)
+			return line
+		}
+		baudParagraph = newStatusLine()
+		deviceParagraph = newStatusLine()
+		connectionParagraph = newStatusLine()
+		readTimeoutParagraph = newStatusLine()
+		framingParagraph = newStatusLine()
+		logsEnabledParagraph = newStatusLine()
+		writtenDataParagraph = newStatusLine()
+		readDataParagraph = newStatusLine()
+		captureParagraph = newStatusLine()
+		pauseParagraph = newStatusLine()
+		localEchoParagraph = newStatusLine()
+		sendEolParagraph = newStatusLine()
+		hexInputParagraph = newStatusLine()
+		if ShowsText(mode) {
+			hexModeParagraph = newStatusLine()
+			encodingParagraph = newStatusLine()
+			filterParagraph = newStatusLine()
+			timestampsEnabledParagraph = newStatusLine()
+			followModeParagraph = newStatusLine()
 		}
+		statusBlock = layoutStatus(statusLines, configStartX, configEndX, availableHeight)
 	}
 
 	var inboxList *widgets.List
-	var inboxPlot *widgets.Plot
+	var inboxPlot *SeriesPlot
+	var plotLegendParagraph *widgets.Paragraph
+	var statsTable *widgets.Table
+	inputStartY := mainEndY + 1
 
-	if mode == Text {
+	listEndY, plotStartY := mainEndY, mainStartY
+	if mode == Split {
+		listEndY = mainStartY + int(float64(mainEndY-mainStartY)*splitRatio)
+		plotStartY = listEndY
+	}
+
+	if mode != Text && mode != Plot && mode != Split {
+		panic("unknown gui mode")
+	}
+	if ShowsText(mode) {
 		inboxList = widgets.NewList()
 		inboxList.TextStyle = ui.NewStyle(ui.ColorWhite)
 		inboxList.WrapText = true
-		inboxList.SetRect(mainStartX, mainStartY, mainEndX, mainEndY)
-		inboxList.Title = fmt.Sprintf("%s(%d)", "IN messages", MAX_MSG_DISPLAY_SIZE)
-	} else if mode == Plot {
-		inboxPlot = widgets.NewPlot()
+		inboxList.SetRect(mainStartX, mainStartY, mainEndX, listEndY)
+		inboxList.Title = fmt.Sprintf("%s(%d)", INBOX_LIST_TITLE, MAX_MSG_DISPLAY_SIZE)
+	}
+	if ShowsPlot(mode) {
+		inboxPlot = NewSeriesPlot()
 		inboxPlot.Title = "IN"
-		inboxPlot.LineColors[0] = ui.ColorYellow
-		inboxPlot.PlotType = widgets.LineChart
-		inboxPlot.Marker = widgets.MarkerDot
-		inboxPlot.SetRect(mainStartX, mainStartY, mainEndX, mainEndY)
-	} else {
-		panic("unknown gui mode")
+		inboxPlot.LineColors = seriesColors
+		inboxPlot.SetRect(mainStartX, plotStartY, mainEndX, mainEndY)
+		plotLegendParagraph = widgets.NewParagraph()
+		plotLegendParagraph.Title = "Legend"
+		plotLegendParagraph.SetRect(mainStartX, mainEndY, mainEndX, mainEndY+PARAGRAPH_HEIGHT)
+		inputStartY += PARAGRAPH_HEIGHT
 	}
 
 	inputWidget := widgets.NewParagraph()
-	inputWidget.SetRect(0, (mainEndY + 1), mainEndX, (mainEndY + (2 * PARAGRAPH_HEIGHT) + 1))
+	inputWidget.SetRect(0, inputStartY, mainEndX, (inputStartY + (2 * PARAGRAPH_HEIGHT)))
 	inputWidget.WrapText = true
 
+	if mode == Plot {
+		statsStartY := inputStartY + (2 * PARAGRAPH_HEIGHT)
+		statsEndY := statsStartY + STATS_TABLE_HEIGHT
+		if statsEndY > availableHeight {
+			statsEndY = availableHeight
+		}
+		statsTable = widgets.NewTable()
+		statsTable.Title = "Statistics"
+		statsTable.RowSeparator = false
+		statsTable.Rows = [][]string{statsHeader}
+		statsTable.SetRect(mainStartX, statsStartY, mainEndX, statsEndY)
+	}
+
 	return &MainGui{
+		StatusBlock:                statusBlock,
 		BaudParagraph:              baudParagraph,
 		DeviceParagraph:            deviceParagraph,
+		ConnectionParagraph:        connectionParagraph,
 		ReadTimeoutParagraph:       readTimeoutParagraph,
+		FramingParagraph:           framingParagraph,
 		LogsEnabledParagraph:       logsEnabledParagraph,
 		TimestampsEnabledParagraph: timestampsEnabledParagraph,
 		HexModeParagraph:           hexModeParagraph,
+		EncodingParagraph:          encodingParagraph,
+		FilterParagraph:            filterParagraph,
 		WrittenDataParagraph:       writtenDataParagraph,
 		ReadDataParagraph:          readDataParagraph,
+		CaptureParagraph:           captureParagraph,
 		PauseParagraph:             pauseParagraph,
+		LocalEchoParagraph:         localEchoParagraph,
+		SendEolParagraph:           sendEolParagraph,
+		HexInputParagraph:          hexInputParagraph,
 		FollowModeParagraph:        followModeParagraph,
 		InboxList:                  inboxList,
 		InboxPlot:                  inboxPlot,
+		PlotLegendParagraph:        plotLegendParagraph,
+		StatsTable:                 statsTable,
 		InputParagraph:             inputWidget,
 	}
 }
 
+// layoutStatus puts the status lines one per row into a single frame, which
+// fits into the height. Lines which don't fit are left out.
+func layoutStatus(lines []*widgets.Paragraph, startX int, endX int, height int) *ui.Block {
+	block := ui.NewBlock()
+	block.Title = STATUS_TITLE
+	block.SetRect(startX, 0, endX, min(len(lines)+2, height))
+	for i, line := range lines {
+		y := block.Inner.Min.Y + i
+		if y < block.Inner.Max.Y {
+			line.SetRect(block.Inner.Min.X, y, block.Inner.Max.X, y+1)
+		}
+	}
+	return block
+}
+
+// newStatusLineParagraph creates a borderless single row paragraph. Without a
+// border the padding is negative, so the text takes the whole rectangle and the
+// paragraph doesn't clear the frame around it.
+func newStatusLineParagraph() *widgets.Paragraph {
+	line := widgets.NewParagraph()
+	line.Border = false
+	line.PaddingLeft, line.PaddingTop, line.PaddingRight, line.PaddingBottom = -1, -1, -1, -1
+	return line
+}
+
 func (g *MainGui) Render() {
 	var guiWidgets []ui.Drawable
 	appendWidgetIfNotNull := func(w ui.Drawable) {
@@ -160,22 +261,86 @@ func (g *MainGui) Render() {
 			guiWidgets = append(guiWidgets, w)
 		}
 	}
+	appendWidgetIfNotNull(g.StatusBlock)
 	appendWidgetIfNotNull(g.BaudParagraph)
 	appendWidgetIfNotNull(g.DeviceParagraph)
+	appendWidgetIfNotNull(g.ConnectionParagraph)
 	appendWidgetIfNotNull(g.ReadTimeoutParagraph)
+	appendWidgetIfNotNull(g.FramingParagraph)
 	appendWidgetIfNotNull(g.WrittenDataParagraph)
 	appendWidgetIfNotNull(g.ReadDataParagraph)
+	appendWidgetIfNotNull(g.CaptureParagraph)
 	appendWidgetIfNotNull(g.InputParagraph)
 	appendWidgetIfNotNull(g.PauseParagraph)
+	appendWidgetIfNotNull(g.LocalEchoParagraph)
+	appendWidgetIfNotNull(g.SendEolParagraph)
+	appendWidgetIfNotNull(g.HexInputParagraph)
 	appendWidgetIfNotNull(g.LogsEnabledParagraph)
 	appendWidgetIfNotNull(g.TimestampsEnabledParagraph)
 	appendWidgetIfNotNull(g.HexModeParagraph)
+	appendWidgetIfNotNull(g.EncodingParagraph)
+	appendWidgetIfNotNull(g.FilterParagraph)
 	appendWidgetIfNotNull(g.InboxList)
 	appendWidgetIfNotNull(g.InboxPlot)
+	appendWidgetIfNotNull(g.PlotLegendParagraph)
+	appendWidgetIfNotNull(g.StatsTable)
 	appendWidgetIfNotNull(g.FollowModeParagraph)
 	ui.Render(guiWidgets...)
 }
 
+// SetSeries puts the points of the series on the plot, each in its own
+// colour, and lists the series in the legend.
+func (g *MainGui) SetSeries(series []*utils.Series, data [][]PlotPoint) {
+	legend := make([]string, len(series))
+	for i, s := range series {
+		legend[i] = fmt.Sprintf("[■ %s](fg:%s)", s.Label, seriesColorNames[i%len(seriesColorNames)])
+	}
+	g.InboxPlot.Data = data
+	g.PlotLegendParagraph.Text = strings.Join(legend, "  ")
+}
+
+// SetStats lists the statistics of the series, each row in the colour of its
+// series on the plot.
+func (g *MainGui) SetStats(stats []utils.SeriesStats, title string) {
+	if g.StatsTable == nil {
+		return
+	}
+	rows := [][]string{statsHeader}
+	g.StatsTable.RowStyles = make(map[int]ui.Style)
+	for i, s := range stats {
+		rows = append(rows, []string{
+			s.Label,
+			fmt.Sprintf("%.3f", s.Min),
+			fmt.Sprintf("%.3f", s.Max),
+			fmt.Sprintf("%.3f", s.Mean),
+			fmt.Sprintf("%.3f", s.StdDev),
+			fmt.Sprintf("%.3f", s.Last),
+			fmt.Sprintf("%.2f", s.RateHz),
+		})
+		g.StatsTable.RowStyles[i+1] = ui.NewStyle(seriesColors[i%len(seriesColors)])
+	}
+	g.StatsTable.Rows = rows
+	g.StatsTable.Title = title
+}
+
+// SetFocus highlights the border of the pane (TEXT or PLOT) receiving keys in
+// SPLIT mode.
+func (g *MainGui) SetFocus(pane string) {
+	focusedStyle := ui.NewStyle(ui.ColorYellow)
+	if g.InboxList != nil {
+		g.InboxList.BorderStyle = ui.Theme.Block.Border
+		if pane == Text {
+			g.InboxList.BorderStyle = focusedStyle
+		}
+	}
+	if g.InboxPlot != nil {
+		g.InboxPlot.BorderStyle = ui.Theme.Block.Border
+		if pane == Plot {
+			g.InboxPlot.BorderStyle = focusedStyle
+		}
+	}
+}
+
 func Init() {
 	utils.Must("init ui", ui.Init())
 }
@@ -0,0 +1,65 @@
+package gui
+
+import (
+	"image"
+	"testing"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+func TestLayoutStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   int
+		height  int
+		shown   int
+		blockY2 int
+	}{
+		{"fits", 18, 40, 18, 20},
+		{"exactly fits", 18, 20, 18, 20},
+		{"short terminal", 18, 12, 10, 12},
+	}
+	for _, tt := range tests {
+		lines := make([]*widgets.Paragraph, tt.lines)
+		for i := range lines {
+			lines[i] = newStatusLineParagraph()
+			lines[i].Text = "Status"
+		}
+		block := layoutStatus(lines, 60, 80, tt.height)
+		if block.Min.Y != 0 || block.Max.Y != tt.blockY2 || block.Max.Y > tt.height {
+			t.Errorf("%s: frame spans rows %d-%d, want 0-%d", tt.name, block.Min.Y, block.Max.Y, tt.blockY2)
+		}
+		for i, line := range lines {
+			rect := line.GetRect()
+			if i >= tt.shown {
+				if !rect.Empty() {
+					t.Errorf("%s: line %d not fitting the terminal is laid out at %v", tt.name, i, rect)
+				}
+				continue
+			}
+			want := image.Rect(61, 1+i, 79, 2+i)
+			if rect != want || line.Inner != want {
+				t.Errorf("%s: line %d at %v (inner %v), want %v", tt.name, i, rect, line.Inner, want)
+			}
+		}
+	}
+}
+
+func TestStatusLineDrawsInsideFrame(t *testing.T) {
+	line := newStatusLineParagraph()
+	line.Text = "Pause: false"
+	line.SetRect(1, 1, 20, 2)
+	buf := ui.NewBuffer(line.GetRect())
+	line.Draw(buf)
+	for x, r := range "Pause: false" {
+		if got := buf.GetCell(image.Pt(1+x, 1)).Rune; got != r {
+			t.Fatalf("cell %d = %q, want %q", x, got, r)
+		}
+	}
+	for point := range buf.CellMap {
+		if !point.In(image.Rect(1, 1, 20, 2)) {
+			t.Errorf("status line draws outside of its row at %v", point)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package gui
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+const (
+	Y_LABELS_WIDTH = 10
+	X_LABELS_GAP   = 2
+)
+
+type PlotPoint struct {
+	X float64
+	Y float64
+}
+
+// SeriesPlot is a braille line chart with explicit axes ranges. Unlike
+// widgets.Plot it places points by their X value and supports negative and
+// offset Y ranges.
+type SeriesPlot struct {
+	ui.Block
+
+	Data       [][]PlotPoint
+	LineColors []ui.Color
+
+	XMin float64
+	XMax float64
+	YMin float64
+	YMax float64
+
+	// XLabel formats the X axis labels, defaults to %g
+	XLabel func(x float64) string
+
+	ShowCursor  bool
+	CursorX     float64
+	CursorColor ui.Color
+}
+
+func NewSeriesPlot() *SeriesPlot {
+	return &SeriesPlot{
+		Block:       *ui.NewBlock(),
+		LineColors:  ui.Theme.Plot.Lines,
+		CursorColor: ui.ColorRed,
+		XMax:        1,
+		YMax:        1,
+	}
+}
+
+func (p *SeriesPlot) Draw(buf *ui.Buffer) {
+	p.Block.Draw(buf)
+	drawArea := image.Rect(p.Inner.Min.X+Y_LABELS_WIDTH+1, p.Inner.Min.Y, p.Inner.Max.X, p.Inner.Max.Y-2)
+	if drawArea.Dx() < 2 || drawArea.Dy() < 2 {
+		return
+	}
+	p.drawAxes(buf, drawArea)
+	if p.ShowCursor {
+		p.drawCursor(buf, drawArea)
+	}
+
+	canvas := ui.NewCanvas()
+	canvas.Rectangle = drawArea
+	for i, points := range p.Data {
+		color := ui.SelectColor(p.LineColors, i)
+		for j, point := range points {
+			if j == 0 || len(points) == 1 {
+				if p.contains(point) {
+					canvas.SetPoint(p.toCanvasPoint(drawArea, point), color)
+				}
+				continue
+			}
+			if from, to, visible := p.clipSegment(points[j-1], point); visible {
+				canvas.SetLine(p.toCanvasPoint(drawArea, from), p.toCanvasPoint(drawArea, to), color)
+			}
+		}
+	}
+	canvas.Draw(buf)
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+func (p *SeriesPlot) contains(point PlotPoint) bool {
+	xMin, xMax := p.xRange()
+	yMin, yMax := p.yRange()
+	return point.X >= xMin && point.X <= xMax && point.Y >= yMin && point.Y <= yMax
+}
+
+// clipSegment cuts the segment to the axes ranges (Liang-Barsky), points
+// outside of them would fall off the canvas. Segments with non finite
+// coordinates are not visible.
+func (p *SeriesPlot) clipSegment(from PlotPoint, to PlotPoint) (PlotPoint, PlotPoint, bool) {
+	for _, v := range []float64{from.X, from.Y, to.X, to.Y} {
+		if !isFinite(v) {
+			return from, to, false
+		}
+	}
+	xMin, xMax := p.xRange()
+	yMin, yMax := p.yRange()
+	dx, dy := to.X-from.X, to.Y-from.Y
+	tMin, tMax := 0.0, 1.0
+	edges := []struct{ p, q float64 }{
+		{-dx, from.X - xMin},
+		{dx, xMax - from.X},
+		{-dy, from.Y - yMin},
+		{dy, yMax - from.Y},
+	}
+	for _, edge := range edges {
+		if edge.p == 0 {
+			if edge.q < 0 {
+				return from, to, false
+			}
+			continue
+		}
+		t := edge.q / edge.p
+		if edge.p < 0 {
+			tMin = math.Max(tMin, t)
+		} else {
+			tMax = math.Min(tMax, t)
+		}
+		if tMin > tMax {
+			return from, to, false
+		}
+	}
+	clipped := func(t float64) PlotPoint {
+		return PlotPoint{X: from.X + t*dx, Y: from.Y + t*dy}
+	}
+	return clipped(tMin), clipped(tMax), true
+}
+
+func (p *SeriesPlot) drawCursor(buf *ui.Buffer, drawArea image.Rectangle) {
+	x := p.toCanvasPoint(drawArea, PlotPoint{X: p.CursorX}).X / 2
+	if x < drawArea.Min.X || x >= drawArea.Max.X {
+		return
+	}
+	for y := drawArea.Min.Y; y < drawArea.Max.Y; y++ {
+		buf.SetCell(ui.NewCell(ui.VERTICAL_LINE, ui.NewStyle(p.CursorColor)), image.Pt(x, y))
+	}
+}
+
+// toCanvasPoint maps a point to braille canvas coordinates, which have 2x
+// horizontal and 4x vertical resolution of terminal cells.
+func (p *SeriesPlot) toCanvasPoint(drawArea image.Rectangle, point PlotPoint) image.Point {
+	xMin, xMax := p.xRange()
+	yMin, yMax := p.yRange()
+	width := float64(drawArea.Dx()*2 - 1)
+	height := float64(drawArea.Dy()*4 - 1)
+	x := drawArea.Min.X*2 + int((point.X-xMin)/(xMax-xMin)*width)
+	y := drawArea.Max.Y*4 - 1 - int((point.Y-yMin)/(yMax-yMin)*height)
+	return image.Pt(x, y)
+}
+
+func (p *SeriesPlot) drawAxes(buf *ui.Buffer, drawArea image.Rectangle) {
+	axisStyle := ui.NewStyle(ui.ColorWhite)
+	axisX := drawArea.Min.X - 1
+	axisY := drawArea.Max.Y
+	buf.SetCell(ui.NewCell(ui.BOTTOM_LEFT, axisStyle), image.Pt(axisX, axisY))
+	for x := drawArea.Min.X; x < drawArea.Max.X; x++ {
+		buf.SetCell(ui.NewCell(ui.HORIZONTAL_DASH, axisStyle), image.Pt(x, axisY))
+	}
+	for y := drawArea.Min.Y; y < drawArea.Max.Y; y++ {
+		buf.SetCell(ui.NewCell(ui.VERTICAL_DASH, axisStyle), image.Pt(axisX, y))
+	}
+
+	yMin, yMax := p.yRange()
+	for row := 0; row < drawArea.Dy(); row += 2 {
+		value := yMin + (yMax-yMin)*float64(row)/float64(drawArea.Dy()-1)
+		label := fmt.Sprintf("%*.*f", Y_LABELS_WIDTH, 2, value)
+		if len(label) > Y_LABELS_WIDTH {
+			label = fmt.Sprintf("%*.3g", Y_LABELS_WIDTH, value)
+		}
+		buf.SetString(label, axisStyle, image.Pt(p.Inner.Min.X, drawArea.Max.Y-1-row))
+	}
+
+	xMin, xMax := p.xRange()
+	xLabel := p.XLabel
+	if xLabel == nil {
+		xLabel = func(x float64) string {
+			return fmt.Sprintf("%g", x)
+		}
+	}
+	for x := drawArea.Min.X; x < drawArea.Max.X; {
+		value := xMin + (xMax-xMin)*float64(x-drawArea.Min.X)/float64(drawArea.Dx()-1)
+		label := xLabel(value)
+		if x+len(label) > drawArea.Max.X {
+			break
+		}
+		buf.SetString(label, axisStyle, image.Pt(x, axisY+1))
+		x += len(label) + X_LABELS_GAP
+	}
+}
+
+// xRange and yRange guard against empty and non finite ranges, which can't
+// be scaled.
+func (p *SeriesPlot) xRange() (float64, float64) {
+	if !isFinite(p.XMin) || !isFinite(p.XMax) {
+		return 0, 1
+	}
+	if p.XMax <= p.XMin {
+		return p.XMin, p.XMin + 1
+	}
+	return p.XMin, p.XMax
+}
+
+func (p *SeriesPlot) yRange() (float64, float64) {
+	if !isFinite(p.YMin) || !isFinite(p.YMax) {
+		return 0, 1
+	}
+	if p.YMax <= p.YMin {
+		return p.YMin - 0.5, p.YMin + 0.5
+	}
+	return p.YMin, p.YMax
+}
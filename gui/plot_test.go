@@ -0,0 +1,54 @@
+package gui
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	ui "github.com/gizak/termui/v3"
+)
+
+func TestSeriesPlotDrawClipsOutOfRangePoints(t *testing.T) {
+	tests := []struct {
+		name string
+		data [][]PlotPoint
+	}{
+		{"above range", [][]PlotPoint{{{X: 0, Y: 0.5}, {X: 1, Y: 5}, {X: 2, Y: 0.2}}}},
+		{"below range", [][]PlotPoint{{{X: 0, Y: -3}, {X: 1, Y: -4}}}},
+		{"single point outside", [][]PlotPoint{{{X: 1, Y: 5}}}},
+		{"outside x range", [][]PlotPoint{{{X: -10, Y: 0.5}, {X: 20, Y: 0.5}}}},
+		{"nan", [][]PlotPoint{{{X: 0, Y: math.NaN()}, {X: 1, Y: 0.5}}}},
+		{"inf", [][]PlotPoint{{{X: 0, Y: math.Inf(1)}, {X: 1, Y: 0.5}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plot := NewSeriesPlot()
+			plot.SetRect(0, 0, 60, 20)
+			plot.XMin, plot.XMax = 0, 2
+			plot.YMin, plot.YMax = 0, 1
+			plot.Data = tt.data
+			plot.Draw(ui.NewBuffer(image.Rect(0, 0, 60, 20)))
+		})
+	}
+}
+
+func TestSeriesPlotDrawNanRange(t *testing.T) {
+	plot := NewSeriesPlot()
+	plot.SetRect(0, 0, 60, 20)
+	plot.YMin, plot.YMax = math.NaN(), math.NaN()
+	plot.Data = [][]PlotPoint{{{X: 0, Y: 1}, {X: 1, Y: 2}}}
+	plot.Draw(ui.NewBuffer(image.Rect(0, 0, 60, 20)))
+}
+
+func TestClipSegment(t *testing.T) {
+	plot := NewSeriesPlot()
+	plot.XMin, plot.XMax = 0, 10
+	plot.YMin, plot.YMax = 0, 10
+	from, to, visible := plot.clipSegment(PlotPoint{X: 0, Y: 5}, PlotPoint{X: 10, Y: 15})
+	if !visible || from != (PlotPoint{X: 0, Y: 5}) || to != (PlotPoint{X: 5, Y: 10}) {
+		t.Errorf("got %v %v %v, want {0 5} {5 10} true", from, to, visible)
+	}
+	if _, _, visible := plot.clipSegment(PlotPoint{X: 0, Y: 11}, PlotPoint{X: 10, Y: 12}); visible {
+		t.Error("segment above the range should not be visible")
+	}
+}
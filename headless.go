@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"byeduck.com/serial-monitor/utils"
+)
+
+var headless bool
+var headlessTimestamps bool
+var headlessHex bool
+var headlessStdin bool
+
+// headlessDisconnects receives the cause of a lost port in headless mode,
+// where there is nobody to wait for a reconnect
+var headlessDisconnects = make(chan error, 1)
+
+func validateHeadlessFlags() {
+	if headless && portFlag == "" && matchFlag == "" && replayFile == "" {
+		log.Fatalln("--headless needs --port, --match or --replay")
+	}
+}
+
+// runHeadless streams received messages to stdout and stdin lines to the
+// port, until stdin is closed, a signal is received or the port is lost. A
+// replay runs until its end, stdin is not read as there is no port to write.
+func runHeadless() error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	var lines chan string
+	if headlessStdin && !isReplaying() {
+		lines = make(chan string)
+		go readStdinLines(lines)
+	}
+	for {
+		select {
+		case msg := <-msgBuff:
+			if err := printHeadlessMessage(msg); err != nil {
+				return nil
+			}
+		case <-replayEnds:
+			log.Println("Replay finished, exiting")
+			for {
+				select {
+				case msg := <-msgBuff:
+					if err := printHeadlessMessage(msg); err != nil {
+						return nil
+					}
+				default:
+					return nil
+				}
+			}
+		case line, ok := <-lines:
+			if !ok {
+				log.Println("Stdin closed, exiting")
+				return nil
+			}
+			if serialPort == nil {
+				break
+			}
+			// echoed directly, this goroutine is the one emptying msgBuff
+			if msg := writePayload([]byte(line)); msg != nil && localEcho {
+				if err := printHeadlessMessage(msg); err != nil {
+					return nil
+				}
+			}
+		case sig := <-signals:
+			log.Printf("Received %v, exiting\n", sig)
+			return nil
+		case err := <-headlessDisconnects:
+			return fmt.Errorf("serial port %s disconnected: %w", portName, err)
+		}
+	}
+}
+
+func printHeadlessMessage(msg *utils.Message) error {
+	_, err := fmt.Fprintln(os.Stdout, formatHeadlessMessage(msg))
+	if err != nil {
+		log.Printf("Cannot write to stdout: %v\n", err)
+	}
+	return err
+}
+
+// readStdinLines sends stdin lines without line endings, the channel is
+// closed on EOF
+func readStdinLines(lines chan<- string) {
+	defer close(lines)
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			lines <- strings.TrimRight(line, "\r\n")
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("Cannot read stdin: %v\n", err)
+			}
+			return
+		}
+	}
+}
+
+func formatHeadlessMessage(msg *utils.Message) string {
+	var builder strings.Builder
+	if headlessTimestamps {
+		builder.WriteString(msg.Timestamp.Format(utils.CAPTURE_TIME_FORMAT))
+		builder.WriteRune(' ')
+	}
+	if localEcho {
+		if msg.Direction == utils.TX {
+			builder.WriteString(">> ")
+		} else {
+			builder.WriteString("<< ")
+		}
+	}
+	if headlessHex {
+		builder.WriteString(utils.FormatHexBytes(msg.Data))
+	} else {
+		builder.WriteString(utils.MessageText(msg, textEncoding))
+	}
+	return builder.String()
+}
@@ -2,39 +2,66 @@ package main
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"container/list"
 
 	"byeduck.com/serial-monitor/gui"
+	"byeduck.com/serial-monitor/source"
 	"byeduck.com/serial-monitor/utils"
 	ui "github.com/gizak/termui/v3"
 	"go.bug.st/serial"
+	"go.bug.st/serial/enumerator"
 )
 
 const (
-	INPUT_PREFIX                 = ">> "
-	TEXT_NAVIGATION_INSTRUCTIONS = "i - enter input mode; h - hex mode; c - clear messages; s - print timestamps; j - scroll down; k - scroll up; t - scroll to top; b - scroll to bottom; f - enter/exit fallow mode, p - pause/unpause; m - change mode; z - zoom in/out; ESC - exit"
-	PLOT_NAVIGATION_INSTRUCTIONS = "i - enter input mode; c - clear messages; p - pause/unpause; m - change mode; z - zoom in/out; ESC - exit"
+	INPUT_PREFIX                   = ">> "
+	HEX_INPUT_PREFIX               = "HEX>> "
+	TEXT_NAVIGATION_INSTRUCTIONS   = "i - enter input mode; / - search; n/N - next/previous match; g - filter; h - hex mode; e - change text encoding; A - render/raw ANSI escapes; d - change framing; l - local echo; r - change send line ending; x - hex input; w - start/stop capture; c - clear messages; s - print timestamps; j - scroll down; k - scroll up; t - scroll to top; b - scroll to bottom; f - enter/exit fallow mode, p - pause/unpause; m - change mode; z - zoom in/out; ESC - exit"
+	SPLIT_NAVIGATION_INSTRUCTIONS  = "Tab - switch focused pane; [/] - change split ratio; "
+	REPLAY_NAVIGATION_INSTRUCTIONS = "p - pause/unpause replay; </> - seek replay back/forward; "
+	PLOT_NAVIGATION_INSTRUCTIONS   = "i - enter input mode; d - change framing; l - local echo; r - change send line ending; x - hex input; w - start/stop capture; a - change x axis; y - change y scale; R - reset statistics; f - freeze plot; Left/Right - pan; +/- - zoom; ,/. - move cursor; c - clear messages; p - pause/unpause; m - change mode; z - zoom in/out; ESC - exit"
 
 	MAX_MSG_CAPACITY   = 10000
 	MAX_POINT_CAPACITY = 200
 	MSG_BUFF_SIZE      = 1000
+	CHUNK_BUFF_SIZE    = 1000
+
+	RECONNECT_INTERVAL    = 500 * time.Millisecond
+	PLOT_REFRESH_INTERVAL = 200 * time.Millisecond
+
+	SPLIT_RATIO_STEP = 0.1
+	MIN_SPLIT_RATIO  = 0.2
+	MAX_SPLIT_RATIO  = 0.8
 )
 
 var baud int
+var dataBits int
+var parity string
+var stopBits string
+var lineFrame string
+var portFlag string
+var matchFlag string
 var readTimeoutMillieconds int
+var framingSpec string
+var encodeSend bool
 var logsEnabled bool
 var guiMode string
+var splitRatio float64
+var focusedPane string
 
 var writtenBytes int64
 var readBytes int64
@@ -44,19 +71,65 @@ var paused bool
 var fullScreen bool
 var printTime bool
 var hexMode bool
+var localEcho bool
+var sendEol string
+var hexInput bool
+var historyFile string
+var inputEditor *utils.LineEditor
+var textEncoding string
+var renderAnsi bool
+var colorRulesFile string
+var colorRules []utils.ColorRule
+var connected bool
+var reconnecting bool
+
+// reconnectStop ends the reconnect loop, reconnectDone is closed once it has
+// returned
+var reconnectStop chan struct{}
+var reconnectDone chan struct{}
 
-var serialPort serial.Port
+var serialPort source.Source
+
+// openSource opens the port and listPorts finds ports, they can be swapped
+// for a mock or a pty
+var openSource source.Opener = source.OpenSerial
+var listPorts source.Lister = source.ListSerial
+var serialMode *serial.Mode
+var portMatcher *utils.PortMatcher
+var portDetails *enumerator.PortDetails
+var serialMutex sync.Mutex
+var readerCancel context.CancelFunc
+var readerDone chan struct{}
 var portName string
 var messages *list.List
+
+// msgBuff passes messages to be shown, nil clears the shown ones when the
+// replay is seeked back
 var msgBuff chan *utils.Message
+var chunkBuff chan []byte
+var framer utils.Framer
+var framerChanges chan utils.Framer
+var framingPresets []string
+var framingErrors atomic.Int64
+var sendEncoder func(payload []byte) []byte
 
 var mainGui *gui.MainGui
 
 func main() {
+	os.Exit(run())
+}
+
+// run returns the exit code, so that deferred cleanup (closing the port, the
+// capture and the log file) runs before exiting
+func run() int {
 	msgBuff = make(chan *utils.Message, MSG_BUFF_SIZE)
+	chunkBuff = make(chan []byte, CHUNK_BUFF_SIZE)
+	framerChanges = make(chan utils.Framer)
 	initFlags()
 	flag.Parse()
 	validateFlags()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	if logsEnabled {
 		logFile, err := os.OpenFile("serial_monitor_logs.log", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
@@ -70,6 +143,10 @@ func main() {
 
 	log.Println("Initializing serial monitor")
 	logFlags()
+	if captureFile != "" {
+		utils.Must("start capture", startCapture())
+	}
+	defer stopCapture()
 
 	inputMode = false
 	followMode = true
@@ -77,34 +154,79 @@ func main() {
 	fullScreen = false
 	printTime = false
 	hexMode = false
+	plotPoints = MAX_POINT_CAPACITY
+	focusedPane = gui.Text
 
-	portName = getPort()
-	openSerial(portName)
-	defer closeSerial()
+	messages = list.New()
+	inputEditor = utils.NewLineEditor()
+	promptEditor = utils.NewLineEditor()
+	if historyFile != "" {
+		if err := inputEditor.LoadHistory(historyFile); err != nil {
+			log.Printf("Cannot load input history: %v\n", err)
+		}
+	}
+	if isReplaying() {
+		loadReplay()
+		portName = REPLAY_PORT_PREFIX + replayFile
+	} else {
+		portName = getPort()
+		portDetails = getPortDetails(portName)
+		openSerial(portName)
+		defer closeSerial()
+		// the device must not be reopened while closing
+		defer stopReconnect()
+	}
+
+	if headless {
+		go frameMessages(ctx, framer)
+		if isReplaying() {
+			go replaySession()
+		}
+		if scriptFile != "" {
+			if !runScript() {
+				return 1
+			}
+			return 0
+		}
+		if err := runHeadless(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
 
 	gui.Init()
 	defer gui.Close()
 	createGui()
 
-	messages = list.New()
 	go handleMessages()
-	go readSerial()
+	go frameMessages(ctx, framer)
+	if isReplaying() {
+		go replaySession()
+	}
 	mainGui.Render()
 
-	var input bytes.Buffer
-	clearInputFn := func() {
-		input.Reset()
-		mainGui.InputParagraph.Text = getInputPrefix()
-		mainGui.Render()
-	}
 	uiEvents := ui.PollEvents()
 	for {
 		e := <-uiEvents
+		if e.Type == ui.ResizeEvent {
+			resizeGui()
+			mainGui.Render()
+			continue
+		}
 		if e.Type != ui.KeyboardEvent {
 			continue
 		}
+		if prompt != "" {
+			handlePromptEvent(e.ID)
+			continue
+		}
 		if e.ID == "<Escape>" {
-			if inputMode {
+			if inputMode && inputEditor.Searching() {
+				inputEditor.CancelSearch()
+				updateInputParagraph()
+				mainGui.Render()
+			} else if inputMode {
 				inputMode = false
 				mainGui.InputParagraph.Text = getInstructions()
 				mainGui.Render()
@@ -114,33 +236,14 @@ func main() {
 				break
 			}
 		} else if inputMode {
-			if e.ID == "<Backspace>" {
-				if input.Len() > 0 {
-					input.Truncate(input.Len() - 1)
-					mainGui.InputParagraph.Text = getInputPrefix() + input.String()
-					mainGui.Render()
-				}
-			} else {
-				if e.ID == "<Enter>" && serialPort != nil {
-					n, err := serialPort.Write(input.Bytes())
-					utils.Must("write to serial", err)
-					writtenBytes += int64(n)
-					clearInputFn()
-					updateWrittenBytesParagraph()
-					mainGui.Render()
-				} else {
-					input.WriteString(uiEventToChar(e.ID))
-					mainGui.InputParagraph.Text = getInputPrefix() + input.String()
-					mainGui.Render()
-				}
-			}
+			handleInputEvent(e.ID)
 		} else {
 			switch e.ID {
 			case "i":
 				if !paused {
 					inputMode = true
-					mainGui.InputParagraph.Text = getInputPrefix() + input.String()
-					if guiMode == gui.Text && messages.Len() > 0 && followMode {
+					updateInputParagraph()
+					if gui.ShowsText(guiMode) && messages.Len() > 0 && followMode {
 						mainGui.InboxList.ScrollBottom()
 					}
 					log.Println("Entering input mode")
@@ -148,29 +251,87 @@ func main() {
 				}
 			case "p":
 				log.Println("Pausing/Unpausing")
-				pauseOrUnpause(portName)
+				if isReplaying() {
+					pauseOrUnpauseReplay()
+					updateConnectionParagraph()
+				} else {
+					pauseOrUnpause(getPortName())
+				}
 				updatePauseParagraph()
 				mainGui.Render()
+			case "<", ">":
+				if isReplaying() {
+					if e.ID == "<" {
+						seekReplay(-REPLAY_SEEK_STEP)
+					} else {
+						seekReplay(REPLAY_SEEK_STEP)
+					}
+					updateConnectionParagraph()
+					mainGui.Render()
+				}
 			case "m":
 				changeGuiMode()
 				mainGui.Render()
+			case "l":
+				localEcho = !localEcho
+				if gui.ShowsText(guiMode) {
+					updateMsgInbox()
+				}
+				updateLocalEchoParagraph()
+				mainGui.Render()
+			case "r":
+				changeSendEol()
+				updateSendEolParagraph()
+				mainGui.Render()
+			case "w":
+				startOrStopCapture()
+				updateCaptureParagraph()
+				mainGui.Render()
+			case "x":
+				hexInput = !hexInput
+				updateHexInputParagraph()
+				mainGui.Render()
+			case "d":
+				changeFraming()
+				updateFramingParagraph()
+				mainGui.Render()
 			case "z":
 				zoomInOut()
 				mainGui.Render()
 			case "c":
-				if guiMode == gui.Text && messages.Len() > 0 {
-					mainGui.InboxList.ScrollTop()
+				clearMessages()
+				mainGui.Render()
+			case "<Tab>":
+				if guiMode == gui.Split {
+					switchFocusedPane()
+					mainGui.Render()
 				}
-				messages = list.New()
-				if guiMode == gui.Text {
-					updateMsgInbox()
-				} else if guiMode == gui.Plot {
-					updatePlot()
+			case "[", "]":
+				if guiMode == gui.Split {
+					if e.ID == "[" {
+						changeSplitRatio(-SPLIT_RATIO_STEP)
+					} else {
+						changeSplitRatio(SPLIT_RATIO_STEP)
+					}
+					mainGui.Render()
 				}
-				mainGui.Render()
 			}
-			if guiMode == gui.Text {
+			if isTextFocused() {
 				switch e.ID {
+				case "/":
+					openPrompt(PROMPT_SEARCH)
+					mainGui.Render()
+				case "g":
+					openPrompt(PROMPT_FILTER)
+					mainGui.Render()
+				case "n", "N":
+					if e.ID == "n" {
+						jumpToMatch(1)
+					} else {
+						jumpToMatch(-1)
+					}
+					updateFollowParagraph()
+					mainGui.Render()
 				case "j":
 					mainGui.InboxList.ScrollHalfPageDown()
 					mainGui.Render()
@@ -197,62 +358,346 @@ func main() {
 					updateMsgInbox()
 					updateHexModeParagraph()
 					mainGui.Render()
+				case "e":
+					changeTextEncoding()
+					updateMsgInbox()
+					updateEncodingParagraph()
+					mainGui.Render()
+				case "A":
+					renderAnsi = !renderAnsi
+					log.Printf("ANSI escapes rendering changed to %v\n", renderAnsi)
+					updateMsgInbox()
+					updateEncodingParagraph()
+					mainGui.Render()
+				}
+			} else if isPlotFocused() {
+				switch e.ID {
+				case "a":
+					changePlotXAxis()
+					updatePlot()
+					mainGui.Render()
+				case "y":
+					changePlotYScale()
+					updatePlot()
+					mainGui.Render()
+				case "R":
+					resetStats()
+					updatePlot()
+					mainGui.Render()
+				case "f":
+					freezeOrUnfreezePlot()
+					updatePlot()
+					mainGui.Render()
+				case "<Left>", "<Right>":
+					if e.ID == "<Left>" {
+						panPlot(-1)
+					} else {
+						panPlot(1)
+					}
+					updatePlot()
+					mainGui.Render()
+				case "+", "=", "-":
+					if e.ID == "-" {
+						zoomPlot(-1)
+					} else {
+						zoomPlot(1)
+					}
+					updatePlot()
+					mainGui.Render()
+				case ",", ".":
+					if e.ID == "," {
+						moveCursor(-1)
+					} else {
+						moveCursor(1)
+					}
+					updatePlot()
+					mainGui.Render()
 				}
 			}
 		}
 	}
+	return 0
+}
+
+func handleInputEvent(eventId string) {
+	if eventId == "<Enter>" && !inputEditor.Searching() {
+		if !sendInput() {
+			return
+		}
+	} else {
+		editLine(inputEditor, eventId)
+	}
+	updateInputParagraph()
+	mainGui.Render()
+}
+
+// editLine applies a line editing key to the editor, Enter only accepts the
+// history search.
+func editLine(editor *utils.LineEditor, eventId string) {
+	switch eventId {
+	case "<Backspace>", "<C-<Backspace>>":
+		editor.Backspace()
+	case "<Delete>":
+		editor.Delete()
+	case "<Left>":
+		editor.Left()
+	case "<Right>":
+		editor.Right()
+	case "<Home>", "<C-a>":
+		editor.Home()
+	case "<End>", "<C-e>":
+		editor.End()
+	case "<Up>":
+		editor.HistoryPrev()
+	case "<Down>":
+		editor.HistoryNext()
+	case "<C-w>":
+		editor.DeleteWord()
+	case "<C-r>":
+		editor.ReverseSearch()
+	case "<Enter>":
+		editor.AcceptSearch()
+	default:
+		editor.Insert(uiEventToChar(eventId))
+	}
+}
+
+// sendInput sends the input line and stores it in the history. Returns false
+// when nothing has been sent.
+func sendInput() bool {
+	if serialPort == nil {
+		return false
+	}
+	payload, err := parseInput(inputEditor.Text())
+	if err != nil {
+		mainGui.InputParagraph.Text = fmt.Sprintf("%s%s  [%v](fg:red)", getInputPrefix(), inputEditor.Text(), err)
+		mainGui.Render()
+		return false
+	}
+	if !sendPayload(payload) {
+		return false
+	}
+	if err := inputEditor.Commit(); err != nil {
+		log.Printf("Cannot save input history: %v\n", err)
+	}
+	updateWrittenBytesParagraph()
+	return true
+}
+
+func updateInputParagraph() {
+	mainGui.InputParagraph.Text = getInputPrefix() + inputEditor.Render()
+}
+
+// parseInput turns the typed text into bytes to be sent, either from hex bytes
+// or from text with escape sequences like \r, \t, \0 or \x02.
+func parseInput(text string) ([]byte, error) {
+	if hexInput {
+		return utils.ParseHexBytes(text)
+	}
+	return utils.UnescapeBytes(text)
+}
+
+// sendPayload writes the payload and passes it with local echo to msgBuff.
+// Returns false when the write failed. Headless and script modes read msgBuff
+// from the sending goroutine, so they use writePayload not to block on a full
+// buffer.
+func sendPayload(payload []byte) bool {
+	msg := writePayload(payload)
+	if msg != nil && localEcho {
+		msgBuff <- msg
+	}
+	return msg != nil
+}
+
+// writePayload appends the line ending (except for hex input, which is sent
+// exactly as typed) and writes the payload to the serial port. Returns the
+// sent message, nil when the write failed.
+func writePayload(payload []byte) *utils.Message {
+	if !hexInput {
+		payload = append(payload, utils.EolBytes(sendEol)...)
+	}
+	n, err := serialPort.Write(encodeOutgoing(payload))
+	if err != nil {
+		handleDisconnect(err)
+		return nil
+	}
+	writtenBytes += int64(n)
+	msg := utils.NewMessage(utils.TX, getPortName(), payload)
+	captureMessage(msg)
+	return msg
 }
 
 func uiEventToChar(eventId string) string {
 	if eventId == "<Space>" {
 		return " "
+	} else if eventId == "<Tab>" {
+		return "\t"
 	} else if len(eventId) > 1 {
 		return ""
 	}
 	return eventId
 }
 
+func changeFraming() {
+	next := framingPresets[0]
+	for i, preset := range framingPresets {
+		if preset == framingSpec {
+			next = framingPresets[(i+1)%len(framingPresets)]
+			break
+		}
+	}
+	newFramer, err := utils.ParseFraming(next)
+	utils.Must("parse framing", err)
+	framingSpec = newFramer.String()
+	sendEncoder = utils.PacketEncoder(framingSpec)
+	framingErrors.Store(0)
+	framerChanges <- newFramer
+	log.Printf("Framing changed to %s\n", framingSpec)
+}
+
+// encodeOutgoing wraps the payload in the current framing when it is a binary
+// packet framing (COBS, SLIP) and sent data encoding is enabled.
+func encodeOutgoing(payload []byte) []byte {
+	if sendEncoder != nil && encodeSend {
+		return sendEncoder(payload)
+	}
+	return payload
+}
+
+func changeSendEol() {
+	eols := utils.GetAvailableEols()
+	for i, eol := range eols {
+		if eol == sendEol {
+			sendEol = eols[(i+1)%len(eols)]
+			break
+		}
+	}
+	log.Printf("Send line ending changed to %s\n", sendEol)
+}
+
+func changeTextEncoding() {
+	encodings := utils.GetAvailableEncodings()
+	for i, encoding := range encodings {
+		if encoding == textEncoding {
+			textEncoding = encodings[(i+1)%len(encodings)]
+			break
+		}
+	}
+	log.Printf("Text encoding changed to %s\n", textEncoding)
+}
+
 func zoomInOut() {
 	fullScreen = !fullScreen
 	restartGui()
 }
 
 func changeGuiMode() {
-	if guiMode == gui.Text {
-		guiMode = gui.Plot
-	} else {
-		guiMode = gui.Text
+	modes := gui.GetAvailableModes()
+	for i, mode := range modes {
+		if mode == guiMode {
+			guiMode = modes[(i+1)%len(modes)]
+			break
+		}
 	}
 	restartGui()
 }
 
+func switchFocusedPane() {
+	if focusedPane == gui.Text {
+		focusedPane = gui.Plot
+	} else {
+		focusedPane = gui.Text
+	}
+	mainGui.SetFocus(focusedPane)
+	if !inputMode {
+		mainGui.InputParagraph.Text = getInstructions()
+	}
+}
+
+func changeSplitRatio(delta float64) {
+	splitRatio = math.Max(MIN_SPLIT_RATIO, math.Min(MAX_SPLIT_RATIO, splitRatio+delta))
+	log.Printf("Split ratio changed to %.1f\n", splitRatio)
+	// only the layout changes, so the terminal doesn't need to be reinitialized
+	ui.Clear()
+	rebuildGui()
+}
+
+// isTextFocused tells whether the keys of the messages list are active
+func isTextFocused() bool {
+	return guiMode == gui.Text || (guiMode == gui.Split && focusedPane == gui.Text)
+}
+
+// isPlotFocused tells whether the keys of the plot are active
+func isPlotFocused() bool {
+	return guiMode == gui.Plot || (guiMode == gui.Split && focusedPane == gui.Plot)
+}
+
 func restartGui() {
-	paused = true
 	gui.Close()
 	gui.Init()
+	rebuildGui()
+}
+
+// resizeGui lays the gui out again for the new terminal dimensions
+func resizeGui() {
+	log.Println("Terminal resized")
+	ui.Clear()
+	rebuildGui()
+}
+
+func rebuildGui() {
+	// keep the list scrolled where it was, unless it follows new messages anyway
+	selectedRow := -1
+	if mainGui.InboxList != nil {
+		selectedRow = mainGui.InboxList.SelectedRow
+	}
 	createGui()
-	if guiMode == gui.Text {
+	updateViews()
+	if mainGui.InboxList != nil && selectedRow >= 0 && !followMode {
+		mainGui.InboxList.SelectedRow = min(selectedRow, max(0, len(mainGui.InboxList.Rows)-1))
+	}
+}
+
+// updateViews refreshes the messages list and/or the plot, whichever is shown
+func updateViews() {
+	if gui.ShowsText(guiMode) {
 		updateMsgInbox()
-	} else {
+	}
+	if gui.ShowsPlot(guiMode) {
 		updatePlot()
 	}
-	paused = false
-	updatePauseParagraph()
 }
 
 func handleMessages() {
-	for msg := range msgBuff {
-		if messages.Len() > MAX_MSG_CAPACITY {
-			messages.Remove(messages.Back())
-		}
-		messages.PushFront(msg)
-		if guiMode == gui.Text {
-			updateMsgInbox()
-		} else if guiMode == gui.Plot {
-			updatePlot()
+	// time based plot moves even when nothing is received
+	plotRefresh := time.NewTicker(PLOT_REFRESH_INTERVAL)
+	defer plotRefresh.Stop()
+	for {
+		select {
+		case msg := <-msgBuff:
+			if msg == nil {
+				// replay has been seeked back
+				clearMessages()
+				mainGui.Render()
+				break
+			}
+			if messages.Len() > MAX_MSG_CAPACITY {
+				messages.Remove(messages.Back())
+			}
+			messages.PushFront(msg)
+			updateViews()
+			updateReadBytesParagraph()
+			updateCaptureParagraph()
+			if isReplaying() {
+				updateConnectionParagraph()
+			}
+			mainGui.Render()
+		case <-plotRefresh.C:
+			if gui.ShowsPlot(guiMode) && plotXAxis == X_AXIS_TIME && !plotFrozen {
+				updatePlot()
+				mainGui.Render()
+			}
 		}
-		updateReadBytesParagraph()
-		mainGui.Render()
 	}
 }
 
@@ -262,6 +707,26 @@ func updateHexModeParagraph() {
 	}
 }
 
+func updateFramingParagraph() {
+	if !fullScreen {
+		if sendEncoder != nil {
+			mainGui.FramingParagraph.Text = fmt.Sprintf("Framing: %s (errors: %d)", framingSpec, framingErrors.Load())
+		} else {
+			mainGui.FramingParagraph.Text = fmt.Sprintf("Framing: %s", framingSpec)
+		}
+	}
+}
+
+func updateEncodingParagraph() {
+	if !fullScreen {
+		ansi := "render"
+		if !renderAnsi {
+			ansi = "raw"
+		}
+		mainGui.EncodingParagraph.Text = fmt.Sprintf("Encoding: %s, ANSI: %s", textEncoding, ansi)
+	}
+}
+
 func updateTimestampsEnabledParagraph() {
 	if !fullScreen {
 		mainGui.TimestampsEnabledParagraph.Text = fmt.Sprintf("Timestamps: %v", printTime)
@@ -280,72 +745,134 @@ func updatePauseParagraph() {
 	}
 }
 
-func updateWrittenBytesParagraph() {
+func updateConnectionParagraph() {
 	if !fullScreen {
-		mainGui.WrittenDataParagraph.Text = fmt.Sprintf("Written [B]: %d", writtenBytes)
+		if isReplaying() {
+			mainGui.ConnectionParagraph.Text = formatReplayStatus()
+		} else if isConnected() {
+			mainGui.ConnectionParagraph.Text = "Connection: connected"
+		} else {
+			mainGui.ConnectionParagraph.Text = "Connection: [disconnected](fg:red)"
+		}
 	}
 }
 
-func updateReadBytesParagraph() {
+func updateLocalEchoParagraph() {
 	if !fullScreen {
-		mainGui.ReadDataParagraph.Text = fmt.Sprintf("Read [B]: %d", readBytes)
+		mainGui.LocalEchoParagraph.Text = fmt.Sprintf("Local echo: %v", localEcho)
 	}
 }
 
-func updatePlot() {
-	mainGui.InboxPlot.Data = convertMsgsToPoints()
+func updateSendEolParagraph() {
+	if !fullScreen {
+		mainGui.SendEolParagraph.Text = fmt.Sprintf("Send EOL: %s", sendEol)
+	}
 }
 
-func convertMsgsToPoints() [][]float64 {
-	points := make([][]float64, 1)
-	var pointsCount int
-	if messages.Len() > MAX_POINT_CAPACITY {
-		pointsCount = MAX_POINT_CAPACITY
-	} else {
-		pointsCount = messages.Len()
+func updateHexInputParagraph() {
+	if !fullScreen {
+		mainGui.HexInputParagraph.Text = fmt.Sprintf("Hex input: %v", hexInput)
+	}
+}
+
+func updateWrittenBytesParagraph() {
+	if !fullScreen {
+		mainGui.WrittenDataParagraph.Text = fmt.Sprintf("Written [B]: %d", writtenBytes)
+	}
+}
+
+func updateReadBytesParagraph() {
+	if !fullScreen {
+		mainGui.ReadDataParagraph.Text = fmt.Sprintf("Read [B]: %d", readBytes)
 	}
-	points[0] = utils.ListToSliceFloat(messages, pointsCount)
-	return points
 }
 
 func updateMsgInbox() {
-	mainGui.InboxList.Rows = utils.ListToSliceMsg(messages, messages.Len(), printTime, hexMode)
+	mainGui.InboxList.Rows, searchRows = utils.ListToSliceMsg(messages, messages.Len(), utils.MsgRenderOptions{
+		PrintTime:     printTime,
+		PrintInHex:    hexMode,
+		Encoding:      textEncoding,
+		ShowDirection: localEcho,
+		Filter:        filterRegex,
+		InvertFilter:  invertFilter,
+		Highlight:     searchRegex,
+		ColorRules:    colorRules,
+		RenderAnsi:    renderAnsi,
+	})
+	updateInboxTitle()
 	if followMode && messages.Len() > 0 {
 		mainGui.InboxList.ScrollBottom()
 	}
 }
 
+func clearMessages() {
+	if gui.ShowsText(guiMode) && messages.Len() > 0 {
+		mainGui.InboxList.ScrollTop()
+	}
+	messages = list.New()
+	resetStickyYRange()
+	updateViews()
+}
+
 func createGui() {
 	log.Printf("Creating gui in %s mode\n", guiMode)
-	mainGui = gui.NewMainGui(guiMode, fullScreen)
+	mainGui = gui.NewMainGui(guiMode, fullScreen, splitRatio)
+	if guiMode == gui.Split {
+		mainGui.SetFocus(focusedPane)
+	}
 
 	if !fullScreen {
-		mainGui.BaudParagraph.Text = fmt.Sprintf("Baud: %d", baud)
-		mainGui.DeviceParagraph.Text = fmt.Sprintf("Device: %s", portName)
+		mainGui.BaudParagraph.Text = fmt.Sprintf("Baud: %d %s", baud, utils.FormatLineFrame(serialMode))
+		mainGui.DeviceParagraph.Text = fmt.Sprintf("Device: %s", getPortName())
 		mainGui.ReadTimeoutParagraph.Text = fmt.Sprintf("Read timeout [ms]: %d", readTimeoutMillieconds)
 		mainGui.LogsEnabledParagraph.Text = fmt.Sprintf("Logs enabled: %v", logsEnabled)
 	}
 	updateWrittenBytesParagraph()
 	updateReadBytesParagraph()
+	updateCaptureParagraph()
 	updatePauseParagraph()
-	if guiMode == gui.Text {
+	updateConnectionParagraph()
+	updateFramingParagraph()
+	updateLocalEchoParagraph()
+	updateSendEolParagraph()
+	updateHexInputParagraph()
+	if gui.ShowsText(guiMode) {
 		updateFollowParagraph()
 		updateHexModeParagraph()
+		updateEncodingParagraph()
 		updateTimestampsEnabledParagraph()
+		updateFilterParagraph()
+	}
+	if prompt != "" {
+		updatePromptParagraph()
+	} else if inputMode {
+		updateInputParagraph()
+	} else {
+		mainGui.InputParagraph.Text = getInstructions()
 	}
-	mainGui.InputParagraph.Text = getInstructions()
 }
 
 func pauseOrUnpause(portName string) {
+	serialMutex.Lock()
+	isConnected, isOpen := connected, serialPort != nil
+	serialMutex.Unlock()
 	if paused {
 		log.Println("Unpausing")
-		if serialPort == nil {
-			openSerial(portName)
+		if !isConnected {
+			// reconnect loop will pick the device up once it is back
+			paused = false
+		} else if !isOpen {
 			paused = false
+			if err := tryOpenSerial(portName); err != nil {
+				// the device has gone away while paused
+				handleDisconnect(err)
+			}
 		}
 	} else {
 		log.Println("Pausing")
-		if serialPort != nil {
+		if !isConnected {
+			paused = true
+		} else if isOpen {
 			paused = true
 			closeSerial()
 		}
@@ -353,42 +880,225 @@ func pauseOrUnpause(portName string) {
 }
 
 func closeSerial() {
+	serialMutex.Lock()
 	if serialPort == nil {
+		serialMutex.Unlock()
 		log.Println("Serial port already closed")
+		return
+	}
+	// the port may be dead already (device unplugged), so failing to drain
+	// or close it is not fatal
+	if err := serialPort.Drain(); err != nil {
+		log.Printf("Cannot drain serial: %v\n", err)
+	}
+	stopReader()
+	if err := serialPort.Close(); err != nil {
+		log.Printf("Cannot close serial: %v\n", err)
 	}
-	utils.Must("drain serial", serialPort.Drain())
-	utils.Must("close serial", serialPort.Close())
 	serialPort = nil
+	done := readerDone
+	serialMutex.Unlock()
+	<-done
 	log.Println("Serial port closed")
 }
 
 func openSerial(portName string) {
+	utils.Must("open serial", tryOpenSerial(portName))
+}
+
+// tryOpenSerial opens the port and makes it the current one, its name is set
+// under serialMutex before the reader starts.
+func tryOpenSerial(name string) error {
+	serialMutex.Lock()
+	defer serialMutex.Unlock()
 	if serialPort != nil {
 		log.Fatalln("serial port hasn't been closed in order to be opened")
 	}
-	var err error
-	serialMode := &serial.Mode{BaudRate: baud}
-	serialPort, err = serial.Open(portName, serialMode)
-	serialPort.SetReadTimeout(time.Duration(int32(readTimeoutMillieconds)) * time.Millisecond)
-	utils.Must("open serial", err)
-	utils.Must("flush", serialPort.Drain())
-	utils.Must("reset input buffer", serialPort.ResetInputBuffer())
-	utils.Must("reset output buffer", serialPort.ResetOutputBuffer())
-	log.Printf("Serial port to %s opened\n", portName)
+	port, err := openSource(name, serialMode)
+	if err != nil {
+		return err
+	}
+	initSteps := []struct {
+		description string
+		fn          func() error
+	}{
+		{"set read timeout", func() error {
+			if readTimeoutMillieconds == 0 {
+				return port.SetReadTimeout(source.NoTimeout)
+			}
+			return port.SetReadTimeout(time.Duration(int32(readTimeoutMillieconds)) * time.Millisecond)
+		}},
+		{"flush", port.Drain},
+		{"reset input buffer", port.ResetInputBuffer},
+		{"reset output buffer", port.ResetOutputBuffer},
+	}
+	for _, step := range initSteps {
+		if err := step.fn(); err != nil {
+			port.Close()
+			return fmt.Errorf("%s: %w", step.description, err)
+		}
+	}
+	serialPort = port
+	portName = name
+	connected = true
+	startReader(port)
+	log.Printf("Serial port to %s opened\n", name)
+	return nil
+}
+
+// getPortName returns the name of the current port, which the reconnect loop
+// may change
+func getPortName() string {
+	serialMutex.Lock()
+	defer serialMutex.Unlock()
+	return portName
+}
+
+// isConnected tells whether the device is connected, the reader and the
+// reconnect loop change it
+func isConnected() bool {
+	serialMutex.Lock()
+	defer serialMutex.Unlock()
+	return connected
+}
+
+// handleDisconnect drops the dead port and starts polling for the device to
+// come back. Safe to call from both the reader and the writer.
+func handleDisconnect(cause error) {
+	serialMutex.Lock()
+	if reconnecting {
+		serialMutex.Unlock()
+		return
+	}
+	log.Printf("Serial port %s disconnected: %v\n", portName, cause)
+	if headless {
+		reconnecting = true
+		if serialPort != nil {
+			stopReader()
+			serialPort.Close()
+			serialPort = nil
+		}
+		serialMutex.Unlock()
+		headlessDisconnects <- cause
+		return
+	}
+	reconnecting = true
+	connected = false
+	stop, done := make(chan struct{}), make(chan struct{})
+	reconnectStop, reconnectDone = stop, done
+	if serialPort != nil {
+		// the device is gone, so draining would fail anyway; the reader exits
+		// on its own once its read fails so it is not waited for here
+		stopReader()
+		serialPort.Close()
+		serialPort = nil
+	}
+	serialMutex.Unlock()
+	if mainGui != nil {
+		updateConnectionParagraph()
+		mainGui.Render()
+	}
+	go reconnectSerial(stop, done)
+}
+
+// reconnectSerial polls for the device until it is reopened or stop is
+// closed, done is closed on return
+func reconnectSerial(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(RECONNECT_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			log.Println("Reconnect stopped")
+			return
+		}
+		if paused {
+			continue
+		}
+		name, found := findDisconnectedPort()
+		if !found {
+			continue
+		}
+		if err := tryOpenSerial(name); err != nil {
+			log.Printf("Cannot reopen serial port %s: %v\n", name, err)
+			continue
+		}
+		serialMutex.Lock()
+		reconnecting = false
+		serialMutex.Unlock()
+		log.Printf("Reconnected to %s\n", name)
+		if mainGui != nil {
+			if !fullScreen {
+				mainGui.DeviceParagraph.Text = fmt.Sprintf("Device: %s", name)
+			}
+			updateConnectionParagraph()
+			mainGui.Render()
+		}
+		return
+	}
+}
+
+// stopReconnect ends the reconnect loop if there is one and waits for it
+func stopReconnect() {
+	serialMutex.Lock()
+	stop, done := reconnectStop, reconnectDone
+	reconnectStop, reconnectDone = nil, nil
+	serialMutex.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// findDisconnectedPort looks for the device that has been lost. USB adapters
+// with a serial number are matched by their identity as they can come back
+// under a different path, everything else by the path it was opened with.
+func findDisconnectedPort() (string, bool) {
+	ports, err := listPorts()
+	if err != nil {
+		log.Printf("Cannot get ports: %v\n", err)
+		return "", false
+	}
+	var matcher *utils.PortMatcher
+	if portDetails != nil && portDetails.IsUSB && portDetails.SerialNumber != "" {
+		matcher = &utils.PortMatcher{VID: portDetails.VID, PID: portDetails.PID, SerialNumber: portDetails.SerialNumber}
+	}
+	for _, port := range ports {
+		if matcher != nil && matcher.Matches(port) {
+			return port.Name, true
+		}
+		if matcher == nil && port.Name == getPortName() {
+			return port.Name, true
+		}
+	}
+	return "", false
 }
 
 func getInstructions() string {
+	prefix := ""
+	if isReplaying() {
+		prefix = REPLAY_NAVIGATION_INSTRUCTIONS
+	}
 	if guiMode == gui.Text {
-		return TEXT_NAVIGATION_INSTRUCTIONS
+		return prefix + TEXT_NAVIGATION_INSTRUCTIONS
 	} else if guiMode == gui.Plot {
-		return PLOT_NAVIGATION_INSTRUCTIONS
+		return prefix + PLOT_NAVIGATION_INSTRUCTIONS
+	} else if guiMode == gui.Split && focusedPane == gui.Text {
+		return prefix + SPLIT_NAVIGATION_INSTRUCTIONS + TEXT_NAVIGATION_INSTRUCTIONS
+	} else if guiMode == gui.Split {
+		return prefix + SPLIT_NAVIGATION_INSTRUCTIONS + PLOT_NAVIGATION_INSTRUCTIONS
 	} else {
 		return ""
 	}
 }
 
 func getInputPrefix() string {
-	if inputMode {
+	if inputMode && hexInput {
+		return HEX_INPUT_PREFIX
+	} else if inputMode {
 		return INPUT_PREFIX
 	} else {
 		return ""
@@ -396,57 +1106,268 @@ func getInputPrefix() string {
 }
 
 func getPort() string {
-	ports, err := serial.GetPortsList()
+	if portFlag != "" {
+		log.Printf("Port given by flag: %s\n", portFlag)
+		return portFlag
+	}
+	ports, err := listPorts()
 	utils.Must("get ports", err)
 	if len(ports) == 0 {
 		log.Fatalln("no serial ports found!")
 	}
+	sort.Slice(ports, func(i, j int) bool {
+		return ports[i].Name < ports[j].Name
+	})
+	if portMatcher != nil {
+		return matchPort(ports)
+	}
 	fmt.Printf("Choose one of given ports (type in number 1-%d):\n", len(ports))
-	sort.Strings(ports)
 	for i, port := range ports {
-		fmt.Printf("%d. %s\n", i+1, port)
+		fmt.Printf("%d. %s\n", i+1, utils.FormatPortDetails(port))
 	}
 	reader := bufio.NewReader(os.Stdin)
 	line, err := reader.ReadString('\n')
 	utils.Must("read user input", err)
-	chosenPosition, err := strconv.Atoi(line[:len(line)-1])
+	chosenPosition, err := strconv.Atoi(strings.TrimSpace(line))
 	utils.Must("chose option", err)
 	if chosenPosition < 1 || chosenPosition > len(ports) {
 		log.Fatalln("invalid chosen port")
 	}
-	log.Printf("Chosen port: %s\n", ports[chosenPosition-1])
-	return ports[chosenPosition-1]
+	log.Printf("Chosen port: %s\n", ports[chosenPosition-1].Name)
+	return ports[chosenPosition-1].Name
 }
 
-func readSerial() {
-	var buff bytes.Buffer
+// getPortDetails returns USB identity of the given port, nil when the port
+// cannot be found by the enumerator.
+func getPortDetails(portName string) *enumerator.PortDetails {
+	ports, err := listPorts()
+	if err != nil {
+		log.Printf("Cannot get port details: %v\n", err)
+		return nil
+	}
+	for _, port := range ports {
+		if port.Name == portName {
+			log.Printf("Port details: %s\n", utils.FormatPortDetails(port))
+			return port
+		}
+	}
+	return nil
+}
+
+func matchPort(ports []*enumerator.PortDetails) string {
+	var matched []*enumerator.PortDetails
+	for _, port := range ports {
+		if portMatcher.Matches(port) {
+			matched = append(matched, port)
+		}
+	}
+	if len(matched) == 0 {
+		log.Fatalf("no serial port matching %s found\n", portMatcher)
+	}
+	if len(matched) > 1 {
+		var names []string
+		for _, port := range matched {
+			names = append(names, utils.FormatPortDetails(port))
+		}
+		log.Fatalf("more than one serial port matching %s found (add serial number to narrow it down): %s\n", portMatcher, strings.Join(names, ", "))
+	}
+	log.Printf("Matched port: %s\n", utils.FormatPortDetails(matched[0]))
+	return matched[0].Name
+}
+
+func startReader(port source.Source) {
+	var ctx context.Context
+	ctx, readerCancel = context.WithCancel(context.Background())
+	readerDone = make(chan struct{})
+	go readSerial(ctx, port, readerDone)
+}
+
+// stopReader cancels the running reader. The caller is responsible for closing
+// the port, which unblocks a pending read.
+func stopReader() {
+	if readerCancel != nil {
+		readerCancel()
+		readerCancel = nil
+	}
+}
+
+func readSerial(ctx context.Context, port source.Source, done chan struct{}) {
+	defer close(done)
 	temp_buff := make([]byte, 512)
 	for {
-		if paused || serialPort == nil {
+		n, err := port.Read(temp_buff)
+		if ctx.Err() != nil {
+			log.Println("Reader stopped")
+			return
+		}
+		if err != nil {
+			handleDisconnect(err)
+			return
+		}
+		if n == 0 {
 			continue
 		}
-		n, _ := serialPort.Read(temp_buff)
-		if n != 0 {
-			readBytes += int64(n)
-			buff.Write(temp_buff[:n])
-		} else {
-			if buff.Len() > 0 {
-				line, err := buff.ReadString('\n')
-				if err != nil {
-					buff.Write([]byte(line))
+		readBytes += int64(n)
+		chunk := make([]byte, n)
+		copy(chunk, temp_buff[:n])
+		select {
+		case chunkBuff <- chunk:
+		case <-ctx.Done():
+			log.Println("Reader stopped")
+			return
+		}
+	}
+}
+
+// frameMessages owns the framer, so it can be swapped at runtime through
+// framerChanges without synchronizing with the reader. It stops with the ctx.
+func frameMessages(ctx context.Context, framer utils.Framer) {
+	send := func(msg *utils.Message) {
+		captureMessage(msg)
+		select {
+		case msgBuff <- msg:
+		case <-ctx.Done():
+		}
+	}
+	emit := func(frames ...[]byte) {
+		for _, frame := range frames {
+			send(utils.NewMessage(utils.RX, getPortName(), frame))
+		}
+	}
+	idleTimer := time.NewTimer(time.Hour)
+	idleTimer.Stop()
+	resetIdleTimer := func() {
+		if !idleTimer.Stop() {
+			select {
+			case <-idleTimer.C:
+			default:
+			}
+		}
+		if timeout := framer.IdleTimeout(); timeout > 0 {
+			idleTimer.Reset(timeout)
+		}
+	}
+	var generation int64
+	countErrors := func() {
+		decoder, ok := framer.(utils.DecodingFramer)
+		if ok && int64(decoder.Errors()) != framingErrors.Load() {
+			framingErrors.Store(int64(decoder.Errors()))
+			if mainGui != nil {
+				updateFramingParagraph()
+				mainGui.Render()
+			}
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case chunk := <-chunkBuff:
+			emit(framer.Push(chunk)...)
+			countErrors()
+			resetIdleTimer()
+		case replayed := <-replayBuff:
+			if replayed.generation != replayGeneration.Load() {
+				// replayed before a backward seek
+				break
+			}
+			if replayed.generation != generation {
+				// a backward seek replays from the start, the messages passed
+				// on so far are cleared by the nil following them
+				generation = replayed.generation
+				framer.Flush()
+				select {
+				case msgBuff <- nil:
+				case <-ctx.Done():
+				}
+			}
+			recorded := replayed.msg
+			if recorded == nil {
+				select {
+				case replayEnds <- struct{}{}:
+				default:
+				}
+				break
+			}
+			// replayed messages are stamped with the time they are replayed
+			// at, like those of a real port, so the time plot follows them
+			if recorded.Direction == utils.TX {
+				writtenBytes += int64(len(recorded.Data))
+				tx := utils.NewMessage(utils.TX, getPortName(), recorded.Data)
+				if localEcho {
+					send(tx)
 				} else {
-					msgBuff <- utils.NowMessage(line)
+					captureMessage(tx)
 				}
+				break
+			}
+			// recorded frames are framed again, so the current framing and
+			// decoding is applied as to the data of a real port
+			chunk := utils.FrameBytes(framer, recorded.Data)
+			readBytes += int64(len(chunk))
+			emit(framer.Push(chunk)...)
+			countErrors()
+			resetIdleTimer()
+		case newFramer := <-framerChanges:
+			pending := framer.Flush()
+			framer = newFramer
+			emit(framer.Push(pending)...)
+			resetIdleTimer()
+		case <-idleTimer.C:
+			if frame := framer.Flush(); frame != nil {
+				emit(frame)
 			}
 		}
 	}
 }
 
 func initFlags() {
+	flag.StringVar(&portFlag, "port", "", "Serial port to open (skips the interactive port selection)")
+	flag.StringVar(&matchFlag, "match", "", "Open the USB serial port matching vid:pid[:serial] (skips the interactive port selection)")
 	flag.IntVar(&baud, "baud", 9600, "Baud value")
-	flag.IntVar(&readTimeoutMillieconds, "read-timeout-ms", 10, "Read timeout in milliseconds")
-	flag.StringVar(&guiMode, "mode", "TEXT", "Mode for the gui")
+	flag.IntVar(&dataBits, "data-bits", 8, "Data bits (5, 6, 7 or 8)")
+	flag.StringVar(&parity, "parity", "none", "Parity (none, odd, even, mark or space)")
+	flag.StringVar(&stopBits, "stop-bits", "1", "Stop bits (1, 1.5 or 2)")
+	flag.StringVar(&lineFrame, "frame", "", "Compact data bits, parity and stop bits notation, e.g. 8N1 or 7E1 (overrides --data-bits, --parity and --stop-bits)")
+	flag.StringVar(&framingSpec, "framing", utils.DEFAULT_FRAMING, `Message framing: delim:SEQ (escapes like \r\n, \0 or \x03 allowed), fixed:LEN (bytes), idle:MS (gap ending a frame), cobs or slip`)
+	flag.BoolVar(&encodeSend, "encode-send", false, "Encode data sent in input mode with the current framing (cobs and slip only)")
+	flag.IntVar(&readTimeoutMillieconds, "read-timeout-ms", 10, "Read timeout in milliseconds (0 - block until data arrives)")
+	flag.StringVar(&guiMode, "mode", "TEXT", "Mode for the gui (TEXT, PLOT or SPLIT)")
+	flag.Float64Var(&splitRatio, "split-ratio", 0.5, "Part of the height taken by the messages list in SPLIT mode")
+	flag.StringVar(&plotXAxis, "plot-x", X_AXIS_SAMPLES, "Plot X axis: samples (sample index) or time (message timestamps)")
+	flag.DurationVar(&plotWindow, "plot-window", 10*time.Second, "Time window shown with the time based plot X axis")
+	flag.StringVar(&plotYScale, "plot-y", Y_SCALE_AUTO, "Plot Y axis scale: auto, fixed (range from --plot-y-min and --plot-y-max) or sticky (range only grows)")
+	flag.Float64Var(&plotYMin, "plot-y-min", 0, "Lower bound of the fixed plot Y scale")
+	flag.Float64Var(&plotYMax, "plot-y-max", 0, "Upper bound of the fixed plot Y scale")
+	flag.StringVar(&textEncoding, "encoding", utils.UTF8, "Text encoding of received data (UTF-8, LATIN-1 or ASCII with escaped control characters)")
+	flag.BoolVar(&renderAnsi, "ansi", true, "Render ANSI colour escape sequences of received data (false - show the escapes raw)")
+	flag.StringVar(&colorRulesFile, "color-rules", "", "File with message colour rules, one \"STYLE REGEX\" per line, e.g. \"fg:red,mod:bold ^E:\" (replaces the built-in ERROR/WARN/INFO rules)")
+	flag.StringVar(&captureFile, "capture", "", "Capture sent and received messages with timestamps to the file (w toggles capturing, "+DEFAULT_CAPTURE_NAME+" is used without the flag)")
+	flag.StringVar(&captureFormat, "capture-format", "", "Capture file format: text, csv or jsonl (default - by the file extension)")
+	flag.IntVar(&captureRotateSizeMb, "capture-rotate-size", 0, "Rotate the capture file when it grows over the size in MB (0 - never)")
+	flag.DurationVar(&captureRotateEvery, "capture-rotate-every", 0, "Rotate the capture file after the time, e.g. 1h (0 - never)")
+	flag.StringVar(&replayFile, "replay", "", "Replay a session captured with --capture instead of opening a serial port")
+	flag.StringVar(&replayFormat, "replay-format", "", "Replay file format: text, csv or jsonl (default - by the file extension)")
+	flag.Float64Var(&replaySpeed, "replay-speed", 1, "Replay speed multiplier of the recorded timing (0 - as fast as possible)")
+	flag.BoolVar(&headless, "headless", false, "Run without the gui: print received messages to stdout and send stdin lines to the port (needs --port, --match or --replay)")
+	flag.BoolVar(&headlessTimestamps, "timestamps", false, "Prefix messages printed in headless mode with timestamps")
+	flag.BoolVar(&headlessHex, "hex", false, "Print messages in headless mode as hex bytes")
+	flag.BoolVar(&headlessStdin, "stdin", true, "Send stdin lines to the port in headless mode and exit on stdin EOF (false - only print received messages)")
+	flag.StringVar(&scriptFile, "script", "", "Run the test script (send, expect, sleep and capture steps) headless, exit code is 1 if it fails")
+	flag.StringVar(&junitFile, "junit", "", "Write a JUnit XML report of the --script run to the file")
+	flag.DurationVar(&expectTimeout, "expect-timeout", time.Second, "Timeout of script expect and capture steps without \"within\"")
 	flag.BoolVar(&logsEnabled, "logs", false, "Is logging enabled?")
+	flag.StringVar(&historyFile, "history-file", defaultHistoryFile(), "File the input mode history is kept in (empty - don't persist history)")
+	flag.BoolVar(&localEcho, "local-echo", false, "Show sent data in the message pane")
+	flag.StringVar(&sendEol, "send-eol", utils.EOL_NONE, "Line ending appended to data sent in input mode (none, lf, cr or crlf)")
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".serial_monitor_history")
 }
 
 func validateFlags() {
@@ -456,21 +1377,86 @@ func validateFlags() {
 	if readTimeoutMillieconds < 0 {
 		log.Fatalln("read timeout seconds cannot be negative")
 	}
+	var err error
+	textEncoding, err = utils.ParseEncoding(textEncoding)
+	utils.Must("parse encoding", err)
+	if colorRulesFile != "" {
+		colorRules, err = utils.LoadColorRules(colorRulesFile)
+		utils.Must("load color rules", err)
+	} else {
+		colorRules = utils.DefaultColorRules()
+	}
+	sendEol, err = utils.ParseEol(sendEol)
+	utils.Must("parse send line ending", err)
+	if portFlag != "" && matchFlag != "" {
+		log.Fatalln("--port and --match cannot be used together")
+	}
+	if matchFlag != "" {
+		portMatcher, err = utils.ParsePortMatcher(matchFlag)
+		utils.Must("parse port match", err)
+	}
+	framer, err = utils.ParseFraming(framingSpec)
+	utils.Must("parse framing", err)
+	framingSpec = framer.String()
+	sendEncoder = utils.PacketEncoder(framingSpec)
+	framingPresets = []string{framingSpec}
+	for _, preset := range []string{utils.DEFAULT_FRAMING, `delim:\r\n`, `delim:\r`, `delim:\0`, "idle:100", utils.COBS_FRAMING, utils.SLIP_FRAMING} {
+		if preset != framingSpec {
+			framingPresets = append(framingPresets, preset)
+		}
+	}
+	serialMode = &serial.Mode{BaudRate: baud}
+	if lineFrame != "" {
+		serialMode.DataBits, serialMode.Parity, serialMode.StopBits, err = utils.ParseLineFrame(lineFrame)
+		utils.Must("parse frame", err)
+	} else {
+		serialMode.DataBits, err = utils.ParseDataBits(dataBits)
+		utils.Must("parse data bits", err)
+		serialMode.Parity, err = utils.ParseParity(parity)
+		utils.Must("parse parity", err)
+		serialMode.StopBits, err = utils.ParseStopBits(stopBits)
+		utils.Must("parse stop bits", err)
+	}
 	validMode := true
 	for _, availableMode := range gui.GetAvailableModes() {
 		validMode = strings.EqualFold(guiMode, availableMode)
 		if validMode {
+			guiMode = availableMode
 			break
 		}
 	}
 	if !validMode {
 		log.Fatalln("invalid mode")
 	}
+	if splitRatio < MIN_SPLIT_RATIO || splitRatio > MAX_SPLIT_RATIO {
+		log.Fatalf("split ratio must be between %.1f and %.1f\n", MIN_SPLIT_RATIO, MAX_SPLIT_RATIO)
+	}
+	validatePlotFlags()
+	validateCaptureFlags()
+	validateReplayFlags()
+	validateScriptFlags()
+	validateHeadlessFlags()
 }
 
 func logFlags() {
+	log.Printf("Port: %s\n", portFlag)
+	log.Printf("Port match: %s\n", matchFlag)
 	log.Printf("Baud rate: %d\n", baud)
+	log.Printf("Frame: %s\n", utils.FormatLineFrame(serialMode))
 	log.Printf("Read timeout [ms]: %d\n", readTimeoutMillieconds)
+	log.Printf("Framing: %s\n", framingSpec)
 	log.Printf("Gui mode: %s\n", guiMode)
+	log.Printf("Plot X axis: %s, window: %s\n", plotXAxis, plotWindow)
+	log.Printf("Plot Y scale: %s [%f, %f]\n", plotYScale, plotYMin, plotYMax)
+	log.Printf("Text encoding: %s\n", textEncoding)
+	log.Printf("Render ANSI: %v\n", renderAnsi)
+	log.Printf("Color rules: %v\n", colorRules)
 	log.Printf("Logs enabled: %v\n", logsEnabled)
+	log.Printf("Local echo: %v\n", localEcho)
+	log.Printf("Send EOL: %s\n", sendEol)
+	log.Printf("History file: %s\n", historyFile)
+	log.Printf("Headless: %v, timestamps: %v, hex: %v, stdin: %v\n", headless, headlessTimestamps, headlessHex, headlessStdin)
+	log.Printf("Script: %s, JUnit report: %s, expect timeout: %s\n", scriptFile, junitFile, expectTimeout)
+	log.Printf("Replay: %s (%s), speed: %g\n", replayFile, replayFormat, replaySpeed)
+	log.Printf("Capture: %s (%s), rotate size [MB]: %d, rotate every: %s\n", captureFile, captureFormat, captureRotateSizeMb, captureRotateEvery)
 }
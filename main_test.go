@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"byeduck.com/serial-monitor/source"
+	"byeduck.com/serial-monitor/utils"
+	"go.bug.st/serial"
+)
+
+const (
+	MOCK_PORT    = "/dev/ttyMOCK0"
+	TEST_TIMEOUT = 3 * time.Second
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// startMock points the port seams to a fresh mock and runs frameMessages with
+// the framing spec, without the gui. The port is not opened yet.
+func startMock(t *testing.T, spec string) *source.Mock {
+	t.Helper()
+	mock := source.NewMock(MOCK_PORT)
+	prevOpen, prevList := openSource, listPorts
+	openSource, listPorts = mock.Opener(), mock.Lister()
+
+	var err error
+	framingSpec = spec
+	framer, err = utils.ParseFraming(spec)
+	if err != nil {
+		t.Fatalf("parse framing %q: %v", spec, err)
+	}
+	framingSpec = framer.String()
+	sendEncoder = utils.PacketEncoder(framingSpec)
+	framingErrors.Store(0)
+	mainGui = nil
+	fullScreen = true
+	headless = false
+	portName = MOCK_PORT
+	portDetails = nil
+	serialMode = &serial.Mode{BaudRate: 115200}
+	readTimeoutMillieconds = 0
+	paused, connected, reconnecting = false, false, false
+	localEcho, hexInput, encodeSend = true, false, false
+	sendEol = utils.EOL_NONE
+	writtenBytes, readBytes = 0, 0
+	msgBuff = make(chan *utils.Message, MSG_BUFF_SIZE)
+	chunkBuff = make(chan []byte, CHUNK_BUFF_SIZE)
+	framerChanges = make(chan utils.Framer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	framed := make(chan struct{})
+	go func() {
+		frameMessages(ctx, framer)
+		close(framed)
+	}()
+	t.Cleanup(func() {
+		// the reconnect loop writes the globals the next test resets
+		stopReconnect()
+		if serialPort != nil {
+			closeSerial()
+		}
+		// frameMessages picks the channels up from the globals, so it has to be
+		// gone before the next test replaces them
+		cancel()
+		<-framed
+		openSource, listPorts = prevOpen, prevList
+	})
+	return mock
+}
+
+// receive returns the data of the next count messages
+func receive(t *testing.T, count int) []string {
+	t.Helper()
+	var received []string
+	for len(received) < count {
+		select {
+		case msg := <-msgBuff:
+			received = append(received, string(msg.Data))
+		case <-time.After(TEST_TIMEOUT):
+			t.Fatalf("received %q, waiting for %d messages timed out", received, count)
+		}
+	}
+	return received
+}
+
+// waitFor polls the condition until it holds or the test times out
+func waitFor(t *testing.T, description string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(TEST_TIMEOUT)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", description)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func isReconnected() bool {
+	serialMutex.Lock()
+	defer serialMutex.Unlock()
+	return connected && !reconnecting && serialPort != nil
+}
+
+func TestFramingFromMock(t *testing.T) {
+	tests := []struct {
+		spec   string
+		chunks [][]byte
+		want   []string
+		errors int64
+	}{
+		{`delim:\n`, [][]byte{[]byte("one\ntw"), []byte("o\nthree")}, []string{"one", "two"}, 0},
+		{`delim:\r\n`, [][]byte{[]byte("a\r"), []byte("\nb\r\n")}, []string{"a", "b"}, 0},
+		{"fixed:3", [][]byte{[]byte("abcd"), []byte("efgh")}, []string{"abc", "def"}, 0},
+		{"idle:30", [][]byte{[]byte("ab"), []byte("c")}, []string{"abc"}, 0},
+		{"cobs", [][]byte{utils.CobsEncode([]byte("a\x00b")), {0x05, 0x01, 0x00}, utils.CobsEncode([]byte("c"))}, []string{"a\x00b", "c"}, 1},
+		{"slip", [][]byte{utils.SlipEncode([]byte{utils.SLIP_END}), {utils.SLIP_ESC, 0x01, utils.SLIP_END}, utils.SlipEncode([]byte("d"))}, []string{"\xC0", "d"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			mock := startMock(t, tt.spec)
+			if err := tryOpenSerial(MOCK_PORT); err != nil {
+				t.Fatalf("open mock: %v", err)
+			}
+			for _, chunk := range tt.chunks {
+				mock.Feed(chunk)
+			}
+			if got := receive(t, len(tt.want)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("received %q, want %q", got, tt.want)
+			}
+			waitFor(t, "framing errors", func() bool {
+				return framingErrors.Load() == tt.errors
+			})
+		})
+	}
+}
+
+func TestIdleFramingSplitsOnGap(t *testing.T) {
+	mock := startMock(t, "idle:30")
+	if err := tryOpenSerial(MOCK_PORT); err != nil {
+		t.Fatalf("open mock: %v", err)
+	}
+	mock.Feed([]byte("first"))
+	mock.FeedAfter(200*time.Millisecond, []byte("second"))
+	if got, want := receive(t, 2), []string{"first", "second"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("received %q, want %q", got, want)
+	}
+}
+
+func TestChangeFramingEncodesSentData(t *testing.T) {
+	mock := startMock(t, `delim:\n`)
+	framingPresets = []string{framingSpec, utils.COBS_FRAMING}
+	encodeSend = true
+	if err := tryOpenSerial(MOCK_PORT); err != nil {
+		t.Fatalf("open mock: %v", err)
+	}
+
+	changeFraming()
+	if framingSpec != utils.COBS_FRAMING {
+		t.Fatalf("framing changed to %s, want %s", framingSpec, utils.COBS_FRAMING)
+	}
+	if !sendPayload([]byte{0x11, 0x00}) {
+		t.Fatal("sending failed")
+	}
+	if got, want := mock.Written(), utils.CobsEncode([]byte{0x11, 0x00}); !bytes.Equal(got, want) {
+		t.Errorf("written % X, want % X", got, want)
+	}
+	// the echo shows the payload, not the encoded packet
+	if got := receive(t, 1); got[0] != "\x11\x00" {
+		t.Errorf("echoed %q", got[0])
+	}
+
+	mock.Feed(utils.CobsEncode([]byte("packet")))
+	if got := receive(t, 1); got[0] != "packet" {
+		t.Errorf("received %q after changing framing, want \"packet\"", got[0])
+	}
+}
+
+func TestReconnectAfterDisconnect(t *testing.T) {
+	mock := startMock(t, `delim:\n`)
+	if err := tryOpenSerial(MOCK_PORT); err != nil {
+		t.Fatalf("open mock: %v", err)
+	}
+	mock.Feed([]byte("before\n"))
+	receive(t, 1)
+
+	mock.Disconnect(errors.New("device unplugged"))
+	waitFor(t, "the disconnect", func() bool {
+		serialMutex.Lock()
+		defer serialMutex.Unlock()
+		return !connected && serialPort == nil
+	})
+	// the device is not listed, so the reconnect loop keeps waiting
+	time.Sleep(2 * RECONNECT_INTERVAL)
+	if opens := mock.Opens(); opens != 1 {
+		t.Fatalf("mock opened %d times while unplugged", opens)
+	}
+
+	mock.Reconnect()
+	waitFor(t, "the reconnect", isReconnected)
+	if opens := mock.Opens(); opens != 2 {
+		t.Errorf("mock opened %d times, want 2", opens)
+	}
+	mock.Feed([]byte("after\n"))
+	if got := receive(t, 1); got[0] != "after" {
+		t.Errorf("received %q after reconnecting, want \"after\"", got[0])
+	}
+}
+
+func TestUnpauseUnpluggedDevice(t *testing.T) {
+	mock := startMock(t, `delim:\n`)
+	if err := tryOpenSerial(MOCK_PORT); err != nil {
+		t.Fatalf("open mock: %v", err)
+	}
+
+	pauseOrUnpause(portName)
+	if !paused || serialPort != nil {
+		t.Fatalf("paused: %v, port open: %v", paused, serialPort != nil)
+	}
+	mock.Disconnect(errors.New("device unplugged"))
+	pauseOrUnpause(portName)
+	if paused {
+		t.Fatal("still paused")
+	}
+	waitFor(t, "the reconnect loop", func() bool {
+		serialMutex.Lock()
+		defer serialMutex.Unlock()
+		return reconnecting
+	})
+
+	mock.Reconnect()
+	waitFor(t, "the reconnect", isReconnected)
+	mock.Feed([]byte("back\n"))
+	if got := receive(t, 1); got[0] != "back" {
+		t.Errorf("received %q after reconnecting, want \"back\"", got[0])
+	}
+}
+
+func TestInputMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		hexInput bool
+		eol      string
+		spec     string
+		keys     []string
+		written  []byte
+		echoed   string
+	}{
+		{"text", false, utils.EOL_CRLF, `delim:\n`, []string{"h", "i", "<Space>", "!"}, []byte("hi !\r\n"), "hi !\r\n"},
+		{"escapes", false, utils.EOL_LF, `delim:\n`, []string{"a", "\\", "x", "0", "2"}, []byte("a\x02\n"), "a\x02\n"},
+		{"edited", false, utils.EOL_NONE, `delim:\n`, []string{"a", "c", "<Left>", "b", "<End>", "d", "<Backspace>"}, []byte("abc"), "abc"},
+		{"hex without eol", true, utils.EOL_CRLF, `delim:\n`, []string{"A", "A", "<Space>", "5", "5"}, []byte{0xAA, 0x55}, "\xAA\x55"},
+		{"hex encoded", true, utils.EOL_LF, "slip", []string{"C", "0", "0", "1"}, []byte{utils.SLIP_END, utils.SLIP_ESC, utils.SLIP_ESC_END, 0x01, utils.SLIP_END}, "\xC0\x01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := startMock(t, tt.spec)
+			inputMode, hexInput, sendEol, encodeSend = true, tt.hexInput, tt.eol, true
+			inputEditor = utils.NewLineEditor()
+			if err := tryOpenSerial(MOCK_PORT); err != nil {
+				t.Fatalf("open mock: %v", err)
+			}
+			for _, key := range tt.keys {
+				editLine(inputEditor, key)
+			}
+			if !sendInput() {
+				t.Fatal("nothing sent")
+			}
+			if got := mock.Written(); !bytes.Equal(got, tt.written) {
+				t.Errorf("written % X, want % X", got, tt.written)
+			}
+			if writtenBytes != int64(len(tt.written)) {
+				t.Errorf("written bytes %d, want %d", writtenBytes, len(tt.written))
+			}
+			if got := receive(t, 1); got[0] != tt.echoed {
+				t.Errorf("echoed %q, want %q", got[0], tt.echoed)
+			}
+			if inputEditor.Text() != "" {
+				t.Errorf("input line %q left after sending", inputEditor.Text())
+			}
+		})
+	}
+}
+
+func TestInputModeInvalidHex(t *testing.T) {
+	hexInput = true
+	defer func() { hexInput = false }()
+	for _, text := range []string{"A", "AA 5", "GG"} {
+		if payload, err := parseInput(text); err == nil {
+			t.Errorf("parseInput(%q) = % X, want error", text, payload)
+		}
+	}
+}
+
+func TestInputModeReplies(t *testing.T) {
+	mock := startMock(t, `delim:\r\n`)
+	inputMode, sendEol, localEcho = true, utils.EOL_CRLF, false
+	inputEditor = utils.NewLineEditor()
+	mock.Reply([]byte("AT\r\n"), []byte("OK\r\n"))
+	if err := tryOpenSerial(MOCK_PORT); err != nil {
+		t.Fatalf("open mock: %v", err)
+	}
+	for _, key := range []string{"A", "T"} {
+		editLine(inputEditor, key)
+	}
+	if !sendInput() {
+		t.Fatal("nothing sent")
+	}
+	if got := receive(t, 1); got[0] != "OK" {
+		t.Errorf("received %q, want the reply \"OK\"", got[0])
+	}
+}
+
+func TestScriptSendWithFullBuffer(t *testing.T) {
+	mock := startMock(t, `delim:\n`)
+	sendEol = utils.EOL_LF
+	if err := tryOpenSerial(MOCK_PORT); err != nil {
+		t.Fatalf("open mock: %v", err)
+	}
+	// a chatty device has filled the buffer during a sleep step
+	for len(msgBuff) < cap(msgBuff) {
+		msgBuff <- utils.NewMessage(utils.RX, MOCK_PORT, []byte("noise"))
+	}
+	step, err := utils.ParseScriptStep(`send "AT"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := runScriptStep(step, map[string]string{})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("send step failed: %v", err)
+		}
+	case <-time.After(TEST_TIMEOUT):
+		t.Fatal("send step blocked on the full message buffer")
+	}
+	if got := string(mock.Written()); got != "AT\n" {
+		t.Errorf("written %q, want \"AT\\n\"", got)
+	}
+}
+
+func TestReplaySeekBackDropsQueuedMessages(t *testing.T) {
+	prevMessages, prevBuff := replayMessages, replayBuff
+	t.Cleanup(func() {
+		replayMessages, replayBuff = prevMessages, prevBuff
+		replayGeneration.Store(0)
+	})
+	replayMessages = []*utils.Message{
+		utils.NewMessage(utils.RX, MOCK_PORT, []byte("one\n")),
+		utils.NewMessage(utils.RX, MOCK_PORT, []byte("two\n")),
+		utils.NewMessage(utils.RX, MOCK_PORT, []byte("three\n")),
+	}
+	replayBuff = make(chan replayedMessage, CHUNK_BUFF_SIZE)
+	replayGeneration.Store(0)
+	// still queued when seeking back
+	replayMessage(0, 0)
+	replayMessage(1, 0)
+	if pos, generation := seekReplayTo(2, 1, 0); pos != 1 || generation != 1 {
+		t.Fatalf("seeked to %d in generation %d, want 1 in generation 1", pos, generation)
+	}
+	startMock(t, `delim:\n`)
+
+	var received []string
+	for len(received) < 2 {
+		select {
+		case msg := <-msgBuff:
+			if msg == nil {
+				received = append(received, "<clear>")
+			} else {
+				received = append(received, string(msg.Data))
+			}
+		case <-time.After(TEST_TIMEOUT):
+			t.Fatalf("received %q, waiting for the replay timed out", received)
+		}
+	}
+	if want := []string{"<clear>", "one"}; !reflect.DeepEqual(received, want) {
+		t.Errorf("received %q, want %q", received, want)
+	}
+}
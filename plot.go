@@ -0,0 +1,319 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"byeduck.com/serial-monitor/gui"
+	"byeduck.com/serial-monitor/utils"
+)
+
+const (
+	X_AXIS_SAMPLES = "samples"
+	X_AXIS_TIME    = "time"
+
+	Y_SCALE_AUTO   = "auto"
+	Y_SCALE_FIXED  = "fixed"
+	Y_SCALE_STICKY = "sticky"
+
+	// pan moves the view by a fraction of its width
+	PLOT_PAN_STEPS    = 4
+	PLOT_CURSOR_STEPS = 50
+	MIN_PLOT_POINTS   = 10
+	MIN_PLOT_WINDOW   = 100 * time.Millisecond
+	MAX_PLOT_WINDOW   = time.Hour
+)
+
+var plotXAxis string
+var plotWindow time.Duration
+var plotYScale string
+var plotYMin float64
+var plotYMax float64
+
+// range kept by the sticky Y scale, it only grows until the messages are cleared
+var stickyYMin float64
+var stickyYMax float64
+var stickyYValid bool
+
+var plotPoints int
+
+// frozen plot stops following new messages, it can be panned back through
+// the message history and inspected with the cursor
+var plotFrozen bool
+var plotFrozenAt time.Time
+var plotPanLines int
+var plotPanTime time.Duration
+var plotCursorX float64
+
+// statistics only take samples received after the last reset into account
+var statsResetAt time.Time
+
+func updatePlot() {
+	query, end := plotQuery()
+	series := utils.ListToSeries(messages, query)
+
+	data := make([][]gui.PlotPoint, len(series))
+	xMin, xMax := 0.0, 0.0
+	if plotXAxis == X_AXIS_TIME {
+		xMin = -plotWindow.Seconds()
+	}
+	for i, s := range series {
+		data[i] = make([]gui.PlotPoint, len(s.Values))
+		for j, value := range s.Values {
+			data[i][j] = gui.PlotPoint{X: plotX(s, j, end), Y: value}
+		}
+		if plotXAxis == X_AXIS_SAMPLES {
+			xMax = math.Max(xMax, float64(s.Lines[len(s.Lines)-1]))
+		}
+	}
+	yMin, yMax := plotYRange(series)
+
+	plot := mainGui.InboxPlot
+	plot.XMin, plot.XMax = xMin, xMax
+	plot.YMin, plot.YMax = yMin, yMax
+	if plotXAxis == X_AXIS_TIME && plotFrozen {
+		plot.XLabel = func(x float64) string {
+			return end.Add(time.Duration(x * float64(time.Second))).Format("15:04:05.0")
+		}
+	} else if plotXAxis == X_AXIS_TIME {
+		plot.XLabel = func(x float64) string {
+			return fmt.Sprintf("%.1fs", x)
+		}
+	} else {
+		plot.XLabel = nil
+	}
+	plotCursorX = math.Max(xMin, math.Min(xMax, plotCursorX))
+	plot.ShowCursor = plotFrozen
+	plot.CursorX = plotCursorX
+	var frozen string
+	if plotFrozen {
+		frozen = "FROZEN "
+	}
+	plot.Title = fmt.Sprintf("IN %sx: %s y: [%.2f, %.2f] %s", frozen, formatXRange(xMin, xMax), yMin, yMax, plotYScale)
+	mainGui.SetSeries(series, data)
+	if plotFrozen {
+		mainGui.PlotLegendParagraph.Text += "  " + cursorReadout(series, end)
+	}
+	updateStats(series)
+}
+
+// plotQuery selects the plotted messages, returns the query along with the
+// time of the right edge of the view.
+func plotQuery() (utils.SeriesQuery, time.Time) {
+	query := utils.SeriesQuery{MaxLines: plotPoints}
+	end := time.Now()
+	if plotFrozen {
+		end = plotFrozenAt.Add(-plotPanTime)
+		query.Until = end
+		query.SkipLines = plotPanLines
+	}
+	if plotXAxis == X_AXIS_TIME {
+		query.Since = end.Add(-plotWindow)
+		query.MaxLines = MAX_MSG_CAPACITY
+		query.SkipLines = 0
+	}
+	return query, end
+}
+
+// plotX returns X of the sample, in samples mode the position of its line
+// shared by all the series
+func plotX(s *utils.Series, i int, end time.Time) float64 {
+	if plotXAxis == X_AXIS_TIME {
+		return s.Timestamps[i].Sub(end).Seconds()
+	}
+	return float64(s.Lines[i])
+}
+
+// cursorReadout describes the samples nearest to the cursor
+func cursorReadout(series []*utils.Series, end time.Time) string {
+	var timestamp time.Time
+	var values []string
+	for _, s := range series {
+		nearest := -1
+		for i := range s.Values {
+			if nearest < 0 || math.Abs(plotX(s, i, end)-plotCursorX) < math.Abs(plotX(s, nearest, end)-plotCursorX) {
+				nearest = i
+			}
+		}
+		if nearest < 0 {
+			continue
+		}
+		if timestamp.IsZero() {
+			timestamp = s.Timestamps[nearest]
+		}
+		values = append(values, fmt.Sprintf("%s=%.3f", s.Label, s.Values[nearest]))
+	}
+	if len(values) == 0 {
+		return "| cursor: no data"
+	}
+	return fmt.Sprintf("| cursor [%s]: %s", timestamp.Format(utils.TIME_FORMAT), strings.Join(values, " "))
+}
+
+func freezeOrUnfreezePlot() {
+	plotFrozen = !plotFrozen
+	plotFrozenAt = time.Now()
+	plotPanLines = 0
+	plotPanTime = 0
+	// cursor starts at the newest sample
+	plotCursorX = math.Inf(1)
+	log.Printf("Plot frozen: %v\n", plotFrozen)
+}
+
+// panPlot moves the view back (direction -1) or forward (direction 1) through
+// the message history, freezing the plot first when needed.
+func panPlot(direction int) {
+	if !plotFrozen {
+		freezeOrUnfreezePlot()
+	}
+	if plotXAxis == X_AXIS_TIME {
+		// gaps without plot data are panned through, the view only stops at
+		// the oldest message
+		if query, _ := plotQuery(); direction < 0 && (messages.Len() == 0 || !query.Since.After(oldestMessageTime())) {
+			return
+		}
+		plotPanTime = max(0, plotPanTime-time.Duration(direction)*plotWindow/PLOT_PAN_STEPS)
+		return
+	}
+	panLines := plotPanLines
+	plotPanLines = max(0, plotPanLines-direction*max(1, plotPoints/PLOT_PAN_STEPS))
+	// lines are counted among the plotted ones only, so an empty view means
+	// panning past the oldest of them
+	if query, _ := plotQuery(); len(utils.ListToSeries(messages, query)) == 0 {
+		plotPanLines = panLines
+	}
+}
+
+func oldestMessageTime() time.Time {
+	return messages.Back().Value.(*utils.Message).Timestamp
+}
+
+// zoomPlot narrows (direction 1) or widens (direction -1) the plotted window.
+func zoomPlot(direction int) {
+	if direction > 0 {
+		plotPoints = max(MIN_PLOT_POINTS, plotPoints/2)
+		plotWindow = max(MIN_PLOT_WINDOW, plotWindow/2)
+	} else {
+		plotPoints = min(MAX_MSG_CAPACITY, plotPoints*2)
+		plotWindow = min(MAX_PLOT_WINDOW, plotWindow*2)
+	}
+	log.Printf("Plot window changed to %d points/%s\n", plotPoints, plotWindow)
+}
+
+func moveCursor(direction int) {
+	if !plotFrozen {
+		return
+	}
+	if plotXAxis == X_AXIS_TIME {
+		plotCursorX += float64(direction) * plotWindow.Seconds() / PLOT_CURSOR_STEPS
+	} else {
+		plotCursorX += float64(direction)
+	}
+}
+
+func updateStats(series []*utils.Series) {
+	stats := make([]utils.SeriesStats, len(series))
+	for i, s := range series {
+		stats[i] = utils.ComputeStats(s, statsResetAt)
+	}
+	title := "Statistics"
+	if !statsResetAt.IsZero() {
+		title = fmt.Sprintf("Statistics (since %s)", statsResetAt.Format(utils.TIME_FORMAT))
+	}
+	mainGui.SetStats(stats, title)
+}
+
+func resetStats() {
+	statsResetAt = time.Now()
+	log.Println("Statistics reset")
+}
+
+func plotYRange(series []*utils.Series) (float64, float64) {
+	if plotYScale == Y_SCALE_FIXED {
+		return plotYMin, plotYMax
+	}
+	yMin, yMax := math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for _, value := range s.Values {
+			yMin = math.Min(yMin, value)
+			yMax = math.Max(yMax, value)
+		}
+	}
+	if math.IsInf(yMin, 1) {
+		yMin, yMax = 0, 0
+	}
+	if plotYScale == Y_SCALE_STICKY {
+		if stickyYValid {
+			yMin = math.Min(yMin, stickyYMin)
+			yMax = math.Max(yMax, stickyYMax)
+		}
+		if len(series) > 0 {
+			stickyYMin, stickyYMax, stickyYValid = yMin, yMax, true
+		}
+	}
+	return yMin, yMax
+}
+
+func formatXRange(xMin float64, xMax float64) string {
+	if plotXAxis == X_AXIS_TIME {
+		if plotFrozen {
+			return plotWindow.String()
+		}
+		return fmt.Sprintf("last %s", plotWindow)
+	}
+	return fmt.Sprintf("[%.0f, %.0f]", xMin, xMax)
+}
+
+func resetStickyYRange() {
+	stickyYValid = false
+}
+
+func changePlotXAxis() {
+	if plotXAxis == X_AXIS_SAMPLES {
+		plotXAxis = X_AXIS_TIME
+	} else {
+		plotXAxis = X_AXIS_SAMPLES
+	}
+	plotPanLines = 0
+	plotPanTime = 0
+	log.Printf("Plot X axis changed to %s\n", plotXAxis)
+}
+
+// changePlotYScale cycles through the Y scales, fixed one is available only
+// when its range has been given by flags.
+func changePlotYScale() {
+	switch plotYScale {
+	case Y_SCALE_AUTO:
+		if plotYMin < plotYMax {
+			plotYScale = Y_SCALE_FIXED
+		} else {
+			plotYScale = Y_SCALE_STICKY
+		}
+	case Y_SCALE_FIXED:
+		plotYScale = Y_SCALE_STICKY
+	default:
+		plotYScale = Y_SCALE_AUTO
+	}
+	resetStickyYRange()
+	log.Printf("Plot Y scale changed to %s\n", plotYScale)
+}
+
+func validatePlotFlags() {
+	if plotXAxis != X_AXIS_SAMPLES && plotXAxis != X_AXIS_TIME {
+		log.Fatalln("plot x axis must be samples or time")
+	}
+	if plotWindow <= 0 {
+		log.Fatalln("plot window must be positive")
+	}
+	switch plotYScale {
+	case Y_SCALE_AUTO, Y_SCALE_STICKY:
+	case Y_SCALE_FIXED:
+		if plotYMin >= plotYMax {
+			log.Fatalln("fixed plot y scale requires --plot-y-min lower than --plot-y-max")
+		}
+	default:
+		log.Fatalln("plot y scale must be auto, fixed or sticky")
+	}
+}
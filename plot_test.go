@@ -0,0 +1,55 @@
+package main
+
+import (
+	"container/list"
+	"testing"
+	"time"
+
+	"byeduck.com/serial-monitor/utils"
+)
+
+func TestPanPlotThroughGap(t *testing.T) {
+	plotXAxis, plotWindow, plotPoints, plotFrozen = X_AXIS_TIME, 10*time.Second, 100, false
+	defer func() { plotFrozen = false }()
+	now := time.Now()
+	messages = list.New()
+	for _, age := range []time.Duration{time.Minute, 2 * time.Second, time.Second} {
+		msg := utils.NewMessage(utils.RX, "port", []byte("1"))
+		msg.Timestamp = now.Add(-age)
+		messages.PushFront(msg)
+	}
+
+	// the minute without data is panned through up to the oldest message
+	for i := 0; i < 100 && plotPanTime < time.Hour; i++ {
+		prev := plotPanTime
+		panPlot(-1)
+		if plotPanTime == prev {
+			break
+		}
+	}
+	query, _ := plotQuery()
+	oldest := now.Add(-time.Minute)
+	if query.Since.After(oldest) || query.Until.Before(oldest) {
+		t.Errorf("panning stopped at %s..%s, want the oldest message at %s in view", query.Since, query.Until, oldest)
+	}
+
+	panPlot(1)
+	if query, _ := plotQuery(); !query.Since.After(oldest) {
+		t.Errorf("panning forward didn't move the view")
+	}
+}
+
+func TestPanPlotSamples(t *testing.T) {
+	plotXAxis, plotPoints, plotFrozen = X_AXIS_SAMPLES, 10, false
+	defer func() { plotFrozen = false }()
+	messages = list.New()
+	for i := 0; i < 25; i++ {
+		messages.PushFront(utils.NewMessage(utils.RX, "port", []byte("1")))
+	}
+	for i := 0; i < 20; i++ {
+		panPlot(-1)
+	}
+	if plotPanLines != 24 {
+		t.Errorf("panned back %d lines, want 24 (up to the oldest line)", plotPanLines)
+	}
+}
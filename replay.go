@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"byeduck.com/serial-monitor/utils"
+)
+
+const (
+	REPLAY_PORT_PREFIX = "replay:"
+	REPLAY_SEEK_STEP   = 10 * time.Second
+)
+
+var replayFile string
+var replayFormat string
+var replaySpeed float64
+
+var replayMessages []*utils.Message
+
+// replayPos is the index of the next message to replay
+var replayPos atomic.Int64
+var replayPaused atomic.Bool
+
+// replayWake interrupts waiting for the next message after pausing/unpausing
+var replayWake chan struct{}
+
+// replaySeeks receives replay positions to jump to
+var replaySeeks chan int
+
+// replayBuff passes recorded messages to frameMessages
+var replayBuff chan replayedMessage
+
+// replayGeneration is incremented by each backward seek, messages replayed
+// before it are still queued and are dropped by frameMessages
+var replayGeneration atomic.Int64
+
+// replayedMessage is a recorded message tagged with the generation it has
+// been replayed in, a nil msg marks the end of the replay
+type replayedMessage struct {
+	msg        *utils.Message
+	generation int64
+}
+
+// replayEnds is signalled when all replayed messages have been passed on
+// to msgBuff
+var replayEnds chan struct{}
+
+func isReplaying() bool {
+	return replayFile != ""
+}
+
+func validateReplayFlags() {
+	if replaySpeed < 0 {
+		log.Fatalln("replay speed cannot be negative")
+	}
+	if isReplaying() && (portFlag != "" || matchFlag != "") {
+		log.Fatalln("--replay cannot be used with --port or --match")
+	}
+}
+
+func loadReplay() {
+	var err error
+	replayMessages, err = utils.ReadCapture(replayFile, replayFormat)
+	utils.Must("read replay", err)
+	if len(replayMessages) == 0 {
+		log.Fatalln("replay file has no messages")
+	}
+	replayWake = make(chan struct{}, 1)
+	replaySeeks = make(chan int)
+	replayBuff = make(chan replayedMessage, CHUNK_BUFF_SIZE)
+	replayEnds = make(chan struct{}, 1)
+	log.Printf("Loaded %d messages to replay from %s\n", len(replayMessages), replayFile)
+}
+
+// replaySession feeds the recorded messages to frameMessages, keeping the
+// recorded gaps divided by the speed.
+func replaySession() {
+	pos := 0
+	generation := replayGeneration.Load()
+	for {
+		if replayPaused.Load() || pos >= len(replayMessages) {
+			select {
+			case <-replayWake:
+			case target := <-replaySeeks:
+				pos, generation = seekReplayTo(pos, target, generation)
+			}
+			continue
+		}
+		var delay time.Duration
+		if replaySpeed > 0 && pos > 0 {
+			delay = time.Duration(float64(replayMessages[pos].Timestamp.Sub(replayMessages[pos-1].Timestamp)) / replaySpeed)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			replayMessage(pos, generation)
+			pos++
+			replayPos.Store(int64(pos))
+		case <-replayWake:
+			timer.Stop()
+		case target := <-replaySeeks:
+			timer.Stop()
+			pos, generation = seekReplayTo(pos, target, generation)
+		}
+	}
+}
+
+// seekReplayTo replays messages up to the target without delays. Seeking
+// backwards starts a new generation replayed from the start, frameMessages
+// drops the queued messages of the old one and has the messages cleared.
+func seekReplayTo(pos int, target int, generation int64) (int, int64) {
+	if target < pos {
+		pos = 0
+		generation = replayGeneration.Add(1)
+	}
+	for ; pos < target; pos++ {
+		replayMessage(pos, generation)
+	}
+	replayPos.Store(int64(pos))
+	return pos, generation
+}
+
+// replayMessage hands the message over to frameMessages, which keeps the
+// order of received and sent messages. The last message is followed by nil
+// marking the end.
+func replayMessage(pos int, generation int64) {
+	replayBuff <- replayedMessage{msg: replayMessages[pos], generation: generation}
+	if pos == len(replayMessages)-1 {
+		replayBuff <- replayedMessage{generation: generation}
+	}
+}
+
+func pauseOrUnpauseReplay() {
+	paused = !replayPaused.Load()
+	replayPaused.Store(paused)
+	log.Printf("Replay paused: %v\n", paused)
+	select {
+	case replayWake <- struct{}{}:
+	default:
+	}
+}
+
+// seekReplay moves the replay by the offset from the current position
+func seekReplay(offset time.Duration) {
+	target := replayMessages[0].Timestamp.Add(replayPosition() + offset)
+	next := sort.Search(len(replayMessages), func(i int) bool {
+		return replayMessages[i].Timestamp.After(target)
+	})
+	log.Printf("Seeking replay to %s\n", target.Sub(replayMessages[0].Timestamp))
+	replaySeeks <- next
+}
+
+// replayPosition returns time of the last replayed message from the start
+func replayPosition() time.Duration {
+	pos := int(replayPos.Load())
+	if pos == 0 {
+		return 0
+	}
+	return replayMessages[pos-1].Timestamp.Sub(replayMessages[0].Timestamp)
+}
+
+func formatReplayStatus() string {
+	pos := int(replayPos.Load())
+	total := replayMessages[len(replayMessages)-1].Timestamp.Sub(replayMessages[0].Timestamp)
+	speed := "max"
+	if replaySpeed > 0 {
+		speed = fmt.Sprintf("x%g", replaySpeed)
+	}
+	status := fmt.Sprintf("Replay: %s/%s (%d/%d) %s", formatReplayTime(replayPosition()), formatReplayTime(total), pos, len(replayMessages), speed)
+	if pos >= len(replayMessages) {
+		status += " [done](fg:green)"
+	} else if replayPaused.Load() {
+		status += " [paused](fg:yellow)"
+	}
+	return status
+}
+
+func formatReplayTime(d time.Duration) string {
+	return d.Truncate(100 * time.Millisecond).String()
+}
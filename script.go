@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"byeduck.com/serial-monitor/utils"
+)
+
+const TRANSCRIPT_INDENT = "    "
+
+var scriptFile string
+var junitFile string
+var expectTimeout time.Duration
+var scriptSteps []utils.ScriptStep
+var scriptSignals chan os.Signal
+
+var errInterrupted = errors.New("interrupted")
+
+// validateScriptFlags runs scripts headless, as there is nobody to watch the
+// gui in CI
+func validateScriptFlags() {
+	if scriptFile == "" {
+		if junitFile != "" {
+			log.Fatalln("--junit needs --script")
+		}
+		return
+	}
+	if expectTimeout <= 0 {
+		log.Fatalln("expect timeout must be positive")
+	}
+	var err error
+	scriptSteps, err = utils.LoadScript(scriptFile)
+	utils.Must("load script", err)
+	headless = true
+}
+
+// runScript runs the steps until the first failure, printing the transcript
+// to stdout. Returns false if a step has failed.
+func runScript() bool {
+	scriptSignals = make(chan os.Signal, 1)
+	signal.Notify(scriptSignals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(scriptSignals)
+	vars := map[string]string{}
+	started := time.Now()
+	var cases []utils.JUnitTestCase
+	failed := false
+	for _, step := range scriptSteps {
+		testCase := utils.JUnitTestCase{Name: fmt.Sprintf("%d: %s", step.Line, step), Classname: scriptFile}
+		if failed {
+			testCase.Skipped = &struct{}{}
+			testCase.Time = utils.FormatJUnitTime(0)
+			cases = append(cases, testCase)
+			continue
+		}
+		fmt.Printf("[%d] %s\n", step.Line, step)
+		stepStarted := time.Now()
+		output, err := runScriptStep(step, vars)
+		elapsed := time.Since(stepStarted)
+		testCase.Time = utils.FormatJUnitTime(elapsed)
+		testCase.SystemOut = strings.Join(output, "\n")
+		if err != nil {
+			failed = true
+			testCase.Failure = &utils.JUnitFailure{Message: err.Error(), Text: testCase.SystemOut}
+			fmt.Printf("%sFAIL: %v\n", TRANSCRIPT_INDENT, err)
+		} else {
+			fmt.Printf("%sok (%s)\n", TRANSCRIPT_INDENT, elapsed.Round(time.Millisecond))
+		}
+		cases = append(cases, testCase)
+	}
+	duration := time.Since(started)
+	if failed {
+		fmt.Printf("FAIL %s (%s)\n", scriptFile, duration.Round(time.Millisecond))
+	} else {
+		fmt.Printf("PASS %s, %d steps (%s)\n", scriptFile, len(scriptSteps), duration.Round(time.Millisecond))
+	}
+	if junitFile != "" {
+		suite := utils.NewJUnitTestSuite(scriptFile, started, duration, cases)
+		if err := utils.WriteJUnit(junitFile, suite); err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot write JUnit report: %v\n", err)
+			return false
+		}
+	}
+	return !failed
+}
+
+// runScriptStep returns the transcript lines of the step
+func runScriptStep(step utils.ScriptStep, vars map[string]string) ([]string, error) {
+	var output []string
+	transcript := func(format string, args ...any) {
+		line := fmt.Sprintf(format, args...)
+		fmt.Println(TRANSCRIPT_INDENT + line)
+		output = append(output, line)
+	}
+	switch step.Kind {
+	case utils.STEP_SEND:
+		data, err := step.SendData(vars)
+		if err != nil {
+			return output, err
+		}
+		if serialPort == nil {
+			return output, fmt.Errorf("serial port %s is not open", portName)
+		}
+		transcript(">> %s", utils.EscapeBytes(append(data, utils.EolBytes(sendEol)...)))
+		// not echoed, the transcript shows the sent data already
+		if writePayload(data) == nil {
+			return output, fmt.Errorf("cannot write to serial port %s", portName)
+		}
+	case utils.STEP_SLEEP:
+		return output, scriptWait(step.Duration)
+	case utils.STEP_EXPECT, utils.STEP_CAPTURE:
+		timeout := step.Timeout
+		if timeout == 0 {
+			timeout = expectTimeout
+		}
+		deadline := time.NewTimer(timeout)
+		defer deadline.Stop()
+		for {
+			select {
+			case msg := <-msgBuff:
+				if msg.Direction == utils.TX {
+					continue
+				}
+				text := utils.MessageText(msg, textEncoding)
+				transcript("<< %s", utils.EscapeBytes([]byte(text)))
+				value, matched := step.Capture(text)
+				if !matched {
+					continue
+				}
+				if step.Kind == utils.STEP_CAPTURE {
+					vars[step.Variable] = value
+					transcript("${%s} = %q", step.Variable, value)
+				}
+				return output, nil
+			case <-deadline.C:
+				return output, fmt.Errorf("no message matching /%s/ within %s", step.Pattern, timeout)
+			case <-scriptSignals:
+				return output, errInterrupted
+			case err := <-headlessDisconnects:
+				return output, fmt.Errorf("serial port %s disconnected: %w", portName, err)
+			}
+		}
+	}
+	return output, nil
+}
+
+// scriptWait sleeps, received messages are kept for the following steps
+func scriptWait(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-scriptSignals:
+		return errInterrupted
+	case err := <-headlessDisconnects:
+		return fmt.Errorf("serial port %s disconnected: %w", portName, err)
+	}
+}
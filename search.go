@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"byeduck.com/serial-monitor/gui"
+	"byeduck.com/serial-monitor/utils"
+)
+
+const (
+	PROMPT_SEARCH = "/"
+	PROMPT_FILTER = "filter (!regex to hide matches): "
+)
+
+// prompt is shown in place of the input when it is not empty
+var prompt string
+var promptEditor *utils.LineEditor
+
+var searchRegex *regexp.Regexp
+var searchRows []int
+var filterRegex *regexp.Regexp
+var invertFilter bool
+
+func openPrompt(p string) {
+	prompt = p
+	promptEditor.Reset()
+	updatePromptParagraph()
+}
+
+func closePrompt() {
+	prompt = ""
+	if inputMode {
+		updateInputParagraph()
+	} else {
+		mainGui.InputParagraph.Text = getInstructions()
+	}
+}
+
+func handlePromptEvent(eventId string) {
+	switch eventId {
+	case "<Escape>":
+		if promptEditor.Searching() {
+			promptEditor.CancelSearch()
+			updatePromptParagraph()
+		} else {
+			closePrompt()
+		}
+	case "<Enter>":
+		if promptEditor.Searching() {
+			promptEditor.AcceptSearch()
+			updatePromptParagraph()
+			break
+		}
+		var err error
+		if prompt == PROMPT_SEARCH {
+			err = applySearch(promptEditor.Text())
+		} else {
+			err = applyFilter(promptEditor.Text())
+		}
+		if err != nil {
+			mainGui.InputParagraph.Text = fmt.Sprintf("%s%s  [%v](fg:red)", prompt, promptEditor.Text(), err)
+			break
+		}
+		promptEditor.Commit()
+		closePrompt()
+	default:
+		editLine(promptEditor, eventId)
+		updatePromptParagraph()
+	}
+	mainGui.Render()
+}
+
+func updatePromptParagraph() {
+	mainGui.InputParagraph.Text = prompt + promptEditor.Render()
+}
+
+// applySearch highlights the matches of the regex and jumps to the first one
+// below the current position, empty regex ends the search.
+func applySearch(expr string) error {
+	if expr == "" {
+		searchRegex = nil
+		updateMsgInbox()
+		return nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+	searchRegex = re
+	log.Printf("Searching for %s\n", expr)
+	updateMsgInbox()
+	jumpToMatch(1)
+	updateFollowParagraph()
+	return nil
+}
+
+// applyFilter shows only messages matching the regex, or not matching it when
+// prefixed with !. Empty regex removes the filter.
+func applyFilter(expr string) error {
+	invert := strings.HasPrefix(expr, "!")
+	expr = strings.TrimPrefix(expr, "!")
+	if expr == "" {
+		filterRegex = nil
+		invertFilter = false
+	} else {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return err
+		}
+		filterRegex = re
+		invertFilter = invert
+	}
+	log.Printf("Filter changed to %s\n", formatFilter())
+	updateMsgInbox()
+	updateFilterParagraph()
+	return nil
+}
+
+// jumpToMatch scrolls to the next (direction 1) or previous (direction -1)
+// search match, wrapping around. Follow mode is turned off, so that new
+// messages don't scroll the match away.
+func jumpToMatch(direction int) {
+	if len(searchRows) == 0 {
+		return
+	}
+	current := mainGui.InboxList.SelectedRow
+	target := searchRows[0]
+	if direction < 0 {
+		target = searchRows[len(searchRows)-1]
+	}
+	for i := range searchRows {
+		row := searchRows[i]
+		if direction < 0 {
+			row = searchRows[len(searchRows)-1-i]
+		}
+		if (direction > 0 && row > current) || (direction < 0 && row < current) {
+			target = row
+			break
+		}
+	}
+	followMode = false
+	mainGui.InboxList.SelectedRow = target
+}
+
+func updateInboxTitle() {
+	title := fmt.Sprintf("%s(%d)", gui.INBOX_LIST_TITLE, gui.MAX_MSG_DISPLAY_SIZE)
+	if searchRegex != nil {
+		title = fmt.Sprintf("%s search: /%s/ %d matches", title, searchRegex, len(searchRows))
+	}
+	mainGui.InboxList.Title = title
+}
+
+func updateFilterParagraph() {
+	if !fullScreen {
+		mainGui.FilterParagraph.Text = fmt.Sprintf("Filter: %s", formatFilter())
+	}
+}
+
+func formatFilter() string {
+	if filterRegex == nil {
+		return "none"
+	} else if invertFilter {
+		return fmt.Sprintf("!/%s/", filterRegex)
+	}
+	return fmt.Sprintf("/%s/", filterRegex)
+}
@@ -0,0 +1,241 @@
+package source
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+	"go.bug.st/serial/enumerator"
+)
+
+type mockReply struct {
+	request  []byte
+	response []byte
+}
+
+// Mock is a scripted in-memory source. Data fed to it is returned by reads,
+// writes are recorded and can trigger scripted replies. It can be closed and
+// opened again (pause, reconnect) and listed as a port while plugged in.
+type Mock struct {
+	Name string
+
+	mutex       sync.Mutex
+	pending     []byte
+	written     []byte
+	replies     []mockReply
+	err         error
+	readTimeout time.Duration
+	opens       int
+	dataReady   chan struct{}
+	closed      chan struct{}
+}
+
+// NewMock returns a plugged in, closed mock port
+func NewMock(name string) *Mock {
+	closed := make(chan struct{})
+	close(closed)
+	return &Mock{
+		Name:        name,
+		readTimeout: NoTimeout,
+		dataReady:   make(chan struct{}, 1),
+		closed:      closed,
+	}
+}
+
+// Opener returns an opener handing out the mock when opened by its name,
+// it fails while the mock is disconnected like for an unplugged device
+func (m *Mock) Opener() Opener {
+	return func(name string, mode *serial.Mode) (Source, error) {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		if name != m.Name || m.err != nil {
+			return nil, fmt.Errorf("open %s: %w", name, os.ErrNotExist)
+		}
+		if isClosed(m.closed) {
+			m.closed = make(chan struct{})
+		}
+		m.opens++
+		return m, nil
+	}
+}
+
+// Lister returns a port lister, which lists the mock while it is connected
+func (m *Mock) Lister() Lister {
+	return func() ([]*enumerator.PortDetails, error) {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		if m.err != nil {
+			return nil, nil
+		}
+		return []*enumerator.PortDetails{{Name: m.Name}}, nil
+	}
+}
+
+// Opens returns how many times the mock has been opened
+func (m *Mock) Opens() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.opens
+}
+
+// Feed queues data to be read
+func (m *Mock) Feed(data []byte) {
+	m.mutex.Lock()
+	m.pending = append(m.pending, data...)
+	m.mutex.Unlock()
+	select {
+	case m.dataReady <- struct{}{}:
+	default:
+	}
+}
+
+// FeedAfter queues data to be read after the delay
+func (m *Mock) FeedAfter(delay time.Duration, data []byte) {
+	time.AfterFunc(delay, func() {
+		m.Feed(data)
+	})
+}
+
+// Reply feeds the response whenever a write contains the request
+func (m *Mock) Reply(request []byte, response []byte) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.replies = append(m.replies, mockReply{request: request, response: response})
+}
+
+// Disconnect makes all following reads, writes and opens fail with the
+// error, like with an unplugged device, until Reconnect is called.
+func (m *Mock) Disconnect(err error) {
+	m.mutex.Lock()
+	m.err = err
+	m.mutex.Unlock()
+	select {
+	case m.dataReady <- struct{}{}:
+	default:
+	}
+}
+
+// Reconnect plugs the device back in
+func (m *Mock) Reconnect() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.err = nil
+}
+
+// Written returns all data written so far
+func (m *Mock) Written() []byte {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]byte{}, m.written...)
+}
+
+func (m *Mock) Read(p []byte) (int, error) {
+	for {
+		m.mutex.Lock()
+		closed := m.closed
+		if isClosed(closed) {
+			m.mutex.Unlock()
+			return 0, os.ErrClosed
+		}
+		if err := m.err; err != nil {
+			m.mutex.Unlock()
+			return 0, err
+		}
+		if len(m.pending) > 0 {
+			n := copy(p, m.pending)
+			m.pending = m.pending[n:]
+			m.mutex.Unlock()
+			return n, nil
+		}
+		timeout := m.readTimeout
+		m.mutex.Unlock()
+		if !m.waitForData(timeout, closed) {
+			return 0, nil
+		}
+	}
+}
+
+// waitForData returns false on timeout, true when data may be ready or the mock
+// got closed or disconnected.
+func (m *Mock) waitForData(timeout time.Duration, closed chan struct{}) bool {
+	var timedOut <-chan time.Time
+	if timeout != NoTimeout {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timedOut = timer.C
+	}
+	select {
+	case <-m.dataReady:
+	case <-closed:
+	case <-timedOut:
+		return false
+	}
+	return true
+}
+
+func isClosed(closed chan struct{}) bool {
+	select {
+	case <-closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *Mock) Write(p []byte) (int, error) {
+	m.mutex.Lock()
+	if isClosed(m.closed) {
+		m.mutex.Unlock()
+		return 0, os.ErrClosed
+	}
+	if err := m.err; err != nil {
+		m.mutex.Unlock()
+		return 0, err
+	}
+	m.written = append(m.written, p...)
+	var responses [][]byte
+	for _, reply := range m.replies {
+		if bytes.Contains(p, reply.request) {
+			responses = append(responses, reply.response)
+		}
+	}
+	m.mutex.Unlock()
+	for _, response := range responses {
+		m.Feed(response)
+	}
+	return len(p), nil
+}
+
+func (m *Mock) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if !isClosed(m.closed) {
+		close(m.closed)
+	}
+	return nil
+}
+
+func (m *Mock) SetReadTimeout(t time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.readTimeout = t
+	return nil
+}
+
+func (m *Mock) Drain() error {
+	return nil
+}
+
+func (m *Mock) ResetInputBuffer() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.pending = nil
+	return nil
+}
+
+func (m *Mock) ResetOutputBuffer() error {
+	return nil
+}
@@ -0,0 +1,98 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMockReadWrite(t *testing.T) {
+	mock := NewMock("/dev/mock0")
+	port, err := mock.Opener()("/dev/mock0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port.SetReadTimeout(10 * time.Millisecond)
+	buff := make([]byte, 16)
+	if n, err := port.Read(buff); n != 0 || err != nil {
+		t.Errorf("read without data = %d, %v, want 0, nil", n, err)
+	}
+
+	mock.Reply([]byte("AT"), []byte("OK\r\n"))
+	if _, err := port.Write([]byte("AT\r")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := port.Read(buff); err != nil || string(buff[:n]) != "OK\r\n" {
+		t.Errorf("read reply = %q, %v", buff[:n], err)
+	}
+	if !bytes.Equal(mock.Written(), []byte("AT\r")) {
+		t.Errorf("written = %q", mock.Written())
+	}
+}
+
+func TestMockClosedReadUnblocks(t *testing.T) {
+	mock := NewMock("/dev/mock0")
+	port, _ := mock.Opener()("/dev/mock0", nil)
+	done := make(chan error)
+	go func() {
+		_, err := port.Read(make([]byte, 16))
+		done <- err
+	}()
+	port.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, os.ErrClosed) {
+			t.Errorf("read after close = %v, want %v", err, os.ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("read has not been unblocked by close")
+	}
+}
+
+func TestMockReopen(t *testing.T) {
+	mock := NewMock("/dev/mock0")
+	open := mock.Opener()
+	port, _ := open("/dev/mock0", nil)
+	port.Close()
+	port, err := open("/dev/mock0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.Feed([]byte("data"))
+	buff := make([]byte, 16)
+	if n, err := port.Read(buff); err != nil || string(buff[:n]) != "data" {
+		t.Errorf("read after reopen = %q, %v", buff[:n], err)
+	}
+	if mock.Opens() != 2 {
+		t.Errorf("opens = %d, want 2", mock.Opens())
+	}
+	if _, err := open("/dev/other", nil); err == nil {
+		t.Error("opening a different name should fail")
+	}
+}
+
+func TestMockDisconnect(t *testing.T) {
+	mock := NewMock("/dev/mock0")
+	open := mock.Opener()
+	port, _ := open("/dev/mock0", nil)
+	mock.Disconnect(io.ErrUnexpectedEOF)
+	if _, err := port.Read(make([]byte, 16)); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("read after disconnect = %v", err)
+	}
+	if ports, _ := mock.Lister()(); len(ports) != 0 {
+		t.Errorf("disconnected mock listed: %v", ports)
+	}
+	if _, err := open("/dev/mock0", nil); err == nil {
+		t.Error("opening a disconnected mock should fail")
+	}
+	mock.Reconnect()
+	if ports, _ := mock.Lister()(); len(ports) != 1 || ports[0].Name != "/dev/mock0" {
+		t.Errorf("reconnected mock not listed: %v", ports)
+	}
+	if _, err := open("/dev/mock0", nil); err != nil {
+		t.Errorf("opening a reconnected mock: %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package source
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// Pty is a pseudo-terminal pair. The monitor opens the slave (Name) as a
+// regular serial port, while the master plays the device. The master side
+// is a Source itself, so one monitor can be a device for another one.
+type Pty struct {
+	Master *os.File
+	// Name is the path of the slave side, e.g. /dev/pts/3
+	Name string
+
+	readTimeout time.Duration
+}
+
+func (p *Pty) Read(b []byte) (int, error) {
+	deadline := time.Time{}
+	if p.readTimeout != NoTimeout {
+		deadline = time.Now().Add(p.readTimeout)
+	}
+	if err := p.Master.SetReadDeadline(deadline); err != nil {
+		return 0, err
+	}
+	n, err := p.Master.Read(b)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return n, nil
+	}
+	return n, err
+}
+
+func (p *Pty) Write(b []byte) (int, error) {
+	return p.Master.Write(b)
+}
+
+func (p *Pty) Close() error {
+	return p.Master.Close()
+}
+
+// SetReadTimeout applies to each following read, like with a serial port
+func (p *Pty) SetReadTimeout(t time.Duration) error {
+	p.readTimeout = t
+	return nil
+}
+
+func (p *Pty) Drain() error {
+	return nil
+}
+
+func (p *Pty) ResetInputBuffer() error {
+	return nil
+}
+
+func (p *Pty) ResetOutputBuffer() error {
+	return nil
+}
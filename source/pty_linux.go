@@ -0,0 +1,28 @@
+//go:build linux
+
+package source
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// OpenPty creates a new pseudo-terminal pair
+func OpenPty() (*Pty, error) {
+	fd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("unlock pty: %w", err)
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("get pty number: %w", err)
+	}
+	return &Pty{Master: os.NewFile(uintptr(fd), "/dev/ptmx"), Name: fmt.Sprintf("/dev/pts/%d", n), readTimeout: NoTimeout}, nil
+}
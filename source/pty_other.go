@@ -0,0 +1,10 @@
+//go:build !linux
+
+package source
+
+import "errors"
+
+// OpenPty creates a new pseudo-terminal pair
+func OpenPty() (*Pty, error) {
+	return nil, errors.New("pseudo-terminals are supported only on linux")
+}
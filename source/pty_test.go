@@ -0,0 +1,57 @@
+//go:build linux
+
+package source
+
+import (
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+func TestPtyReadTimeout(t *testing.T) {
+	pty, err := OpenPty()
+	if err != nil {
+		t.Skipf("cannot open pty: %v", err)
+	}
+	defer pty.Close()
+	if err := pty.SetReadTimeout(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	buff := make([]byte, 16)
+	for i := 0; i < 3; i++ {
+		n, err := pty.Read(buff)
+		if n != 0 || err != nil {
+			t.Fatalf("read %d timed out with %d, %v, want 0, nil", i, n, err)
+		}
+	}
+}
+
+func TestPtyPair(t *testing.T) {
+	pty, err := OpenPty()
+	if err != nil {
+		t.Skipf("cannot open pty: %v", err)
+	}
+	defer pty.Close()
+	port, err := OpenSerial(pty.Name, &serial.Mode{BaudRate: 115200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+	port.SetReadTimeout(time.Second)
+	pty.SetReadTimeout(time.Second)
+
+	if _, err := pty.Write([]byte("ping\n")); err != nil {
+		t.Fatal(err)
+	}
+	buff := make([]byte, 16)
+	if n, err := port.Read(buff); err != nil || string(buff[:n]) != "ping\n" {
+		t.Errorf("port read %q, %v, want \"ping\\n\"", buff[:n], err)
+	}
+	if _, err := port.Write([]byte("pong")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := pty.Read(buff); err != nil || string(buff[:n]) != "pong" {
+		t.Errorf("pty read %q, %v, want \"pong\"", buff[:n], err)
+	}
+}
@@ -0,0 +1,41 @@
+// Package source abstracts the byte stream the monitor talks to, so the
+// reading, framing and reconnect logic doesn't depend on real hardware.
+package source
+
+import (
+	"io"
+	"time"
+
+	"go.bug.st/serial"
+	"go.bug.st/serial/enumerator"
+)
+
+// NoTimeout makes reads block until data arrives
+var NoTimeout = serial.NoTimeout
+
+// Source is the subset of serial.Port used by the monitor, with the same
+// semantics: a read timing out returns 0 bytes and no error.
+type Source interface {
+	io.ReadWriteCloser
+	SetReadTimeout(t time.Duration) error
+	// Drain waits until all written data is transmitted.
+	Drain() error
+	ResetInputBuffer() error
+	ResetOutputBuffer() error
+}
+
+// Opener opens the source with the name (e.g. a port path)
+type Opener func(name string, mode *serial.Mode) (Source, error)
+
+// Lister lists the available ports
+type Lister func() ([]*enumerator.PortDetails, error)
+
+// ListSerial lists the real serial ports
+func ListSerial() ([]*enumerator.PortDetails, error) {
+	return enumerator.GetDetailedPortsList()
+}
+
+// OpenSerial opens a real serial port
+func OpenSerial(name string, mode *serial.Mode) (Source, error) {
+	return serial.Open(name, mode)
+}
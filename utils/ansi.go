@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+const ESC = '\x1b'
+
+// ansiColors are the termui names of the SGR colours 0-7
+var ansiColors = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// StyledSpan is a part of text with a termui style, empty style means default.
+type StyledSpan struct {
+	Text  string
+	Style string
+}
+
+type sgrState struct {
+	fg, bg    string
+	bold      bool
+	underline bool
+	reverse   bool
+}
+
+// style returns the state in termui style syntax. termui supports only one
+// modifier, bold is preferred over underline and underline over reverse.
+func (s *sgrState) style() string {
+	var items []string
+	if s.fg != "" {
+		items = append(items, "fg:"+s.fg)
+	}
+	if s.bg != "" {
+		items = append(items, "bg:"+s.bg)
+	}
+	if s.bold {
+		items = append(items, "mod:bold")
+	} else if s.underline {
+		items = append(items, "mod:underline")
+	} else if s.reverse {
+		items = append(items, "mod:reverse")
+	}
+	return strings.Join(items, ",")
+}
+
+func (s *sgrState) apply(params string) {
+	codes := strings.Split(params, ";")
+	for i := 0; i < len(codes); i++ {
+		code, err := strconv.Atoi(codes[i])
+		if codes[i] == "" {
+			code, err = 0, nil
+		}
+		if err != nil {
+			continue
+		}
+		switch {
+		case code == 0:
+			*s = sgrState{}
+		case code == 1:
+			s.bold = true
+		case code == 4:
+			s.underline = true
+		case code == 7:
+			s.reverse = true
+		case code == 22:
+			s.bold = false
+		case code == 24:
+			s.underline = false
+		case code == 27:
+			s.reverse = false
+		case code >= 30 && code <= 37:
+			s.fg = ansiColors[code-30]
+		case code >= 90 && code <= 97:
+			s.fg = ansiColors[code-90]
+		case code == 39:
+			s.fg = ""
+		case code >= 40 && code <= 47:
+			s.bg = ansiColors[code-40]
+		case code >= 100 && code <= 107:
+			s.bg = ansiColors[code-100]
+		case code == 49:
+			s.bg = ""
+		case code == 38 || code == 48:
+			color, skip := extendedColor(codes[i+1:])
+			i += skip
+			if code == 38 {
+				s.fg = color
+			} else {
+				s.bg = color
+			}
+		}
+	}
+}
+
+// extendedColor parses the arguments of 38/48 (5;N or 2;R;G;B). Only the 16
+// basic colours of the 256 colour palette can be shown, others and RGB
+// colours fall back to the default. Returns the colour and the count of
+// arguments consumed.
+func extendedColor(args []string) (string, int) {
+	if len(args) == 0 {
+		return "", 0
+	}
+	switch args[0] {
+	case "5":
+		if len(args) < 2 {
+			return "", len(args)
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 || n > 15 {
+			return "", 2
+		}
+		return ansiColors[n%8], 2
+	case "2":
+		return "", min(4, len(args))
+	}
+	return "", 1
+}
+
+// ParseAnsi splits text on SGR escape sequences into styled spans. Other CSI
+// sequences (cursor movement, erasing, ...) and lone escapes are dropped.
+func ParseAnsi(text string) []StyledSpan {
+	var spans []StyledSpan
+	var state sgrState
+	var builder strings.Builder
+	flush := func() {
+		if builder.Len() > 0 {
+			spans = append(spans, StyledSpan{Text: builder.String(), Style: state.style()})
+			builder.Reset()
+		}
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] != ESC {
+			builder.WriteByte(text[i])
+			continue
+		}
+		if i+1 >= len(text) || text[i+1] != '[' {
+			continue
+		}
+		// CSI: parameter and intermediate bytes end with a final byte 0x40-0x7E
+		end := i + 2
+		for end < len(text) && (text[end] < 0x40 || text[end] > 0x7E) {
+			end++
+		}
+		if end >= len(text) {
+			break
+		}
+		if text[end] == 'm' {
+			flush()
+			state.apply(text[i+2 : end])
+		}
+		i = end
+	}
+	flush()
+	return spans
+}
+
+// EscapeAnsi makes escape characters visible as \x1b
+func EscapeAnsi(text string) string {
+	return strings.ReplaceAll(text, string(ESC), `\x1b`)
+}
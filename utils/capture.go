@@ -0,0 +1,307 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CAPTURE_TEXT  = "text"
+	CAPTURE_CSV   = "csv"
+	CAPTURE_JSONL = "jsonl"
+
+	CAPTURE_TIME_FORMAT   = "2006-01-02T15:04:05.000000Z07:00"
+	ROTATED_SUFFIX_FORMAT = "20060102-150405"
+)
+
+var captureHeader = []string{"timestamp", "direction", "port", "seq", "data"}
+
+func GetAvailableCaptureFormats() []string {
+	return []string{CAPTURE_TEXT, CAPTURE_CSV, CAPTURE_JSONL}
+}
+
+// ParseCaptureFormat validates the format, empty format is guessed from the
+// file extension (.csv, .jsonl or .json, text otherwise).
+func ParseCaptureFormat(format string, path string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			return CAPTURE_CSV, nil
+		case ".jsonl", ".json":
+			return CAPTURE_JSONL, nil
+		}
+		return CAPTURE_TEXT, nil
+	}
+	for _, available := range GetAvailableCaptureFormats() {
+		if strings.EqualFold(format, available) {
+			return available, nil
+		}
+	}
+	return "", fmt.Errorf("invalid capture format %q, expected text, csv or jsonl", format)
+}
+
+// CaptureRecord is a JSON Lines capture entry, data is escaped like in input
+// mode (see EscapeBytes).
+type CaptureRecord struct {
+	Timestamp string `json:"timestamp"`
+	Direction string `json:"direction"`
+	Port      string `json:"port"`
+	Seq       uint64 `json:"seq"`
+	Data      string `json:"data"`
+}
+
+// Capture writes messages to a file as they arrive. The file is rotated
+// (renamed with the time of rotation appended) when it grows over MaxSize
+// bytes or gets older than MaxAge, zero disables the limit. Safe for use from
+// multiple goroutines.
+type Capture struct {
+	Path    string
+	Format  string
+	MaxSize int64
+	MaxAge  time.Duration
+
+	mutex    sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+func OpenCapture(path string, format string, maxSize int64, maxAge time.Duration) (*Capture, error) {
+	c := &Capture{Path: path, Format: format, MaxSize: maxSize, MaxAge: maxAge}
+	if err := c.open(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Capture) open() error {
+	file, err := os.OpenFile(c.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	c.file = file
+	c.size = info.Size()
+	c.openedAt = time.Now()
+	if c.size == 0 && c.Format == CAPTURE_CSV {
+		return c.writeCsv(captureHeader)
+	}
+	return nil
+}
+
+func (c *Capture) Write(msg *Message) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.file == nil {
+		return os.ErrClosed
+	}
+	if err := c.rotateIfNeeded(msg.Timestamp); err != nil {
+		return err
+	}
+	timestamp := msg.Timestamp.Format(CAPTURE_TIME_FORMAT)
+	data := EscapeBytes(msg.Data)
+	switch c.Format {
+	case CAPTURE_CSV:
+		return c.writeCsv([]string{timestamp, msg.Direction.String(), msg.Port, strconv.FormatUint(msg.Seq, 10), data})
+	case CAPTURE_JSONL:
+		line, err := json.Marshal(CaptureRecord{
+			Timestamp: timestamp,
+			Direction: msg.Direction.String(),
+			Port:      msg.Port,
+			Seq:       msg.Seq,
+			Data:      data,
+		})
+		if err != nil {
+			return err
+		}
+		return c.writeString(string(line) + "\n")
+	default:
+		port := msg.Port
+		if port == "" {
+			port = "-"
+		}
+		return c.writeString(fmt.Sprintf("%s %s %s %s\n", timestamp, msg.Direction, port, data))
+	}
+}
+
+func (c *Capture) writeCsv(record []string) error {
+	var builder strings.Builder
+	writer := csv.NewWriter(&builder)
+	writer.Write(record)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return c.writeString(builder.String())
+}
+
+func (c *Capture) writeString(s string) error {
+	n, err := c.file.WriteString(s)
+	c.size += int64(n)
+	return err
+}
+
+func (c *Capture) rotateIfNeeded(now time.Time) error {
+	if (c.MaxSize <= 0 || c.size < c.MaxSize) && (c.MaxAge <= 0 || now.Sub(c.openedAt) < c.MaxAge) {
+		return nil
+	}
+	if c.size == 0 {
+		return nil
+	}
+	if err := c.file.Close(); err != nil {
+		return err
+	}
+	c.file = nil
+	rotated, err := freeRotatedPath(c.Path, now)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(c.Path, rotated); err != nil {
+		return err
+	}
+	return c.open()
+}
+
+// RotatedPath inserts the time before the extension, e.g.
+// capture.csv -> capture-20240131-235959.csv
+func RotatedPath(path string, t time.Time) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(path, ext), t.Format(ROTATED_SUFFIX_FORMAT), ext)
+}
+
+// freeRotatedPath returns RotatedPath, numbered (capture-20240131-235959-1.csv)
+// when files have already been rotated within the same second, so none of them
+// gets overwritten.
+func freeRotatedPath(path string, t time.Time) (string, error) {
+	rotated := RotatedPath(path, t)
+	ext := filepath.Ext(rotated)
+	candidate := rotated
+	for i := 1; ; i++ {
+		_, err := os.Lstat(candidate)
+		if errors.Is(err, os.ErrNotExist) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(rotated, ext), i, ext)
+	}
+}
+
+// Size returns the size of the current (not rotated) file.
+func (c *Capture) Size() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.size
+}
+
+func (c *Capture) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+// ReadCapture reads messages of a capture file in the format (empty format is
+// guessed from the extension).
+func ReadCapture(path string, format string) ([]*Message, error) {
+	format, err := ParseCaptureFormat(format, path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var messages []*Message
+	if format == CAPTURE_CSV {
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = len(captureHeader)
+		for lineNum := 1; ; lineNum++ {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			if lineNum == 1 && record[0] == captureHeader[0] {
+				continue
+			}
+			msg, err := parseCaptureFields(record[0], record[1], record[2], record[4])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, lineNum, err)
+			}
+			messages = append(messages, msg)
+		}
+		return messages, nil
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		var msg *Message
+		if format == CAPTURE_JSONL {
+			var record CaptureRecord
+			if err = json.Unmarshal([]byte(line), &record); err == nil {
+				msg, err = parseCaptureFields(record.Timestamp, record.Direction, record.Port, record.Data)
+			}
+		} else {
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) < 3 {
+				err = fmt.Errorf("expected TIMESTAMP DIRECTION PORT DATA")
+			} else {
+				fields = append(fields, "")
+				if fields[2] == "-" {
+					fields[2] = ""
+				}
+				msg, err = parseCaptureFields(fields[0], fields[1], fields[2], fields[3])
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNum, err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, scanner.Err()
+}
+
+func parseCaptureFields(timestamp string, direction string, port string, data string) (*Message, error) {
+	msg := &Message{Port: port}
+	var err error
+	msg.Timestamp, err = time.Parse(CAPTURE_TIME_FORMAT, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	switch direction {
+	case RX.String():
+		msg.Direction = RX
+	case TX.String():
+		msg.Direction = TX
+	default:
+		return nil, fmt.Errorf("invalid direction %q, expected RX or TX", direction)
+	}
+	msg.Data, err = UnescapeBytes(data)
+	return msg, err
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCaptureRotationWithinSecond(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "capture.txt")
+	capture, err := OpenCapture(path, CAPTURE_TEXT, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer capture.Close()
+	now := time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		msg := NewMessage(RX, "port", []byte("data over the limit"))
+		msg.Timestamp = now.Add(time.Duration(i) * time.Millisecond)
+		if err := capture.Write(msg); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	for _, name := range []string{"capture-20240131-235959.txt", "capture-20240131-235959-1.txt", "capture-20240131-235959-2.txt", "capture.txt"} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("rotated file missing: %v", err)
+		} else if info.Size() == 0 {
+			t.Errorf("%s is empty", name)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("%d capture files, want 4", len(entries))
+	}
+}
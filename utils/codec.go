@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	SLIP_END     = 0xC0
+	SLIP_ESC     = 0xDB
+	SLIP_ESC_END = 0xDC
+	SLIP_ESC_ESC = 0xDD
+)
+
+// CobsEncode encodes the payload with Consistent Overhead Byte Stuffing and
+// appends the 0x00 frame delimiter.
+func CobsEncode(payload []byte) []byte {
+	out := make([]byte, 1, len(payload)+len(payload)/254+2)
+	codeIdx := 0
+	code := byte(1)
+	for i, b := range payload {
+		if b != 0 {
+			out = append(out, b)
+			code++
+		}
+		if b == 0 || code == 0xFF {
+			out[codeIdx] = code
+			code = 1
+			if b != 0 && i == len(payload)-1 {
+				// a full last block needs no code byte after it
+				return append(out, 0)
+			}
+			codeIdx = len(out)
+			out = append(out, 0)
+		}
+	}
+	out[codeIdx] = code
+	return append(out, 0)
+}
+
+// CobsDecode decodes a single COBS frame without the 0x00 delimiter.
+func CobsDecode(frame []byte) ([]byte, error) {
+	out := make([]byte, 0, len(frame))
+	for i := 0; i < len(frame); {
+		code := int(frame[i])
+		if code == 0 {
+			return nil, fmt.Errorf("unexpected zero byte at position %d", i)
+		}
+		i++
+		if i+code-1 > len(frame) {
+			return nil, fmt.Errorf("code %d at position %d overruns frame of length %d", code, i-1, len(frame))
+		}
+		out = append(out, frame[i:i+code-1]...)
+		i += code - 1
+		if code < 0xFF && i < len(frame) {
+			out = append(out, 0)
+		}
+	}
+	return out, nil
+}
+
+// SlipEncode escapes the payload according to RFC 1055 and wraps it in END
+// bytes, the leading one flushes any line noise on the receiver side.
+func SlipEncode(payload []byte) []byte {
+	out := make([]byte, 0, len(payload)+2)
+	out = append(out, SLIP_END)
+	for _, b := range payload {
+		switch b {
+		case SLIP_END:
+			out = append(out, SLIP_ESC, SLIP_ESC_END)
+		case SLIP_ESC:
+			out = append(out, SLIP_ESC, SLIP_ESC_ESC)
+		default:
+			out = append(out, b)
+		}
+	}
+	return append(out, SLIP_END)
+}
+
+// SlipDecode decodes a single SLIP frame without the END delimiter.
+func SlipDecode(frame []byte) ([]byte, error) {
+	out := make([]byte, 0, len(frame))
+	for i := 0; i < len(frame); i++ {
+		if frame[i] != SLIP_ESC {
+			out = append(out, frame[i])
+			continue
+		}
+		i++
+		if i >= len(frame) {
+			return nil, errors.New("frame ends with an escape byte")
+		}
+		switch frame[i] {
+		case SLIP_ESC_END:
+			out = append(out, SLIP_END)
+		case SLIP_ESC_ESC:
+			out = append(out, SLIP_ESC)
+		default:
+			return nil, fmt.Errorf("invalid escape sequence 0x%02X at position %d", frame[i], i)
+		}
+	}
+	return out, nil
+}
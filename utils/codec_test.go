@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func repeatByte(b byte, count int) []byte {
+	return bytes.Repeat([]byte{b}, count)
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, part := range parts {
+		out = append(out, part...)
+	}
+	return out
+}
+
+func TestCobsEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		want    []byte
+	}{
+		{"empty", nil, []byte{0x01, 0x00}},
+		{"zero", []byte{0x00}, []byte{0x01, 0x01, 0x00}},
+		{"two zeros", []byte{0x00, 0x00}, []byte{0x01, 0x01, 0x01, 0x00}},
+		{"zero inside", []byte{0x11, 0x00, 0x22}, []byte{0x02, 0x11, 0x02, 0x22, 0x00}},
+		{"no zero", []byte{0x11, 0x22, 0x33}, []byte{0x04, 0x11, 0x22, 0x33, 0x00}},
+		{"253 bytes", repeatByte(0x01, 253), concat([]byte{0xFE}, repeatByte(0x01, 253), []byte{0x00})},
+		{"254 bytes", repeatByte(0x01, 254), concat([]byte{0xFF}, repeatByte(0x01, 254), []byte{0x00})},
+		{"255 bytes", repeatByte(0x01, 255), concat([]byte{0xFF}, repeatByte(0x01, 254), []byte{0x02, 0x01, 0x00})},
+		{"508 bytes", repeatByte(0x01, 508), concat([]byte{0xFF}, repeatByte(0x01, 254), []byte{0xFF}, repeatByte(0x01, 254), []byte{0x00})},
+		{"zero and 254 bytes", concat([]byte{0x00}, repeatByte(0x01, 254)), concat([]byte{0x01, 0xFF}, repeatByte(0x01, 254), []byte{0x00})},
+		{"254 bytes and zero", concat(repeatByte(0x01, 254), []byte{0x00}), concat([]byte{0xFF}, repeatByte(0x01, 254), []byte{0x01, 0x01, 0x00})},
+	}
+	for _, tt := range tests {
+		if got := CobsEncode(tt.payload); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: CobsEncode() = % X, want % X", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCobsRoundTrip(t *testing.T) {
+	payloads := [][]byte{
+		{},
+		{0x00},
+		{0x00, 0x00, 0x00},
+		[]byte("hello"),
+		{0x00, 0x11, 0x00},
+		repeatByte(0x01, 253),
+		repeatByte(0x01, 254),
+		repeatByte(0x01, 255),
+		repeatByte(0x01, 508),
+		concat(repeatByte(0x01, 254), []byte{0x00}),
+		concat([]byte{0x00}, repeatByte(0x01, 254)),
+		concat(repeatByte(0x01, 300), []byte{0x00}, repeatByte(0x02, 300)),
+	}
+	for _, payload := range payloads {
+		encoded := CobsEncode(payload)
+		if bytes.IndexByte(encoded[:len(encoded)-1], 0) >= 0 || encoded[len(encoded)-1] != 0 {
+			t.Errorf("CobsEncode(% X) = % X, want the only zero byte at the end", payload, encoded)
+			continue
+		}
+		got, err := CobsDecode(encoded[:len(encoded)-1])
+		if err != nil {
+			t.Errorf("CobsDecode(% X) failed: %v", encoded, err)
+			continue
+		}
+		if !bytes.Equal(got, payload) {
+			t.Errorf("COBS round trip of % X = % X", payload, got)
+		}
+	}
+}
+
+func TestCobsDecodeMalformed(t *testing.T) {
+	frames := [][]byte{
+		{0x00},
+		{0x02, 0x11, 0x00, 0x22},
+		{0x05, 0x11, 0x22},
+		{0xFF, 0x01},
+	}
+	for _, frame := range frames {
+		if got, err := CobsDecode(frame); err == nil {
+			t.Errorf("CobsDecode(% X) = % X, want error", frame, got)
+		}
+	}
+}
+
+func TestSlipEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		want    []byte
+	}{
+		{"empty", nil, []byte{SLIP_END, SLIP_END}},
+		{"plain", []byte{0x01, 0x02}, []byte{SLIP_END, 0x01, 0x02, SLIP_END}},
+		{"end", []byte{SLIP_END}, []byte{SLIP_END, SLIP_ESC, SLIP_ESC_END, SLIP_END}},
+		{"esc", []byte{SLIP_ESC}, []byte{SLIP_END, SLIP_ESC, SLIP_ESC_ESC, SLIP_END}},
+		{"escape bytes", []byte{SLIP_ESC_END, SLIP_ESC_ESC}, []byte{SLIP_END, SLIP_ESC_END, SLIP_ESC_ESC, SLIP_END}},
+	}
+	for _, tt := range tests {
+		if got := SlipEncode(tt.payload); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: SlipEncode() = % X, want % X", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSlipRoundTrip(t *testing.T) {
+	payloads := [][]byte{
+		{},
+		[]byte("hello"),
+		{SLIP_END, SLIP_ESC, SLIP_ESC_END, SLIP_ESC_ESC},
+		{SLIP_ESC, SLIP_ESC, SLIP_END, SLIP_END},
+		{0x00, 0xFF},
+	}
+	for _, payload := range payloads {
+		encoded := SlipEncode(payload)
+		got, err := SlipDecode(encoded[1 : len(encoded)-1])
+		if err != nil {
+			t.Errorf("SlipDecode(% X) failed: %v", encoded, err)
+			continue
+		}
+		if !bytes.Equal(got, payload) {
+			t.Errorf("SLIP round trip of % X = % X", payload, got)
+		}
+	}
+}
+
+func TestSlipDecodeMalformed(t *testing.T) {
+	frames := [][]byte{
+		{SLIP_ESC},
+		{0x01, SLIP_ESC},
+		{SLIP_ESC, 0x01},
+		{SLIP_ESC, SLIP_END},
+	}
+	for _, frame := range frames {
+		if got, err := SlipDecode(frame); err == nil {
+			t.Errorf("SlipDecode(% X) = % X, want error", frame, got)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ColorRule styles whole message rows matching the pattern. Style uses the
+// termui style syntax, e.g. fg:red,bg:black,mod:bold.
+type ColorRule struct {
+	Pattern *regexp.Regexp
+	Style   string
+}
+
+func (r ColorRule) String() string {
+	return fmt.Sprintf("%s %s", r.Style, r.Pattern)
+}
+
+var styleColors = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white", "clear"}
+var styleModifiers = []string{"bold", "underline", "reverse"}
+
+// DefaultColorRules returns rules for the usual log level markers, both
+// short (E:, W:, I:) and long (ERROR, WARN, INFO) ones.
+func DefaultColorRules() []ColorRule {
+	return []ColorRule{
+		{Pattern: regexp.MustCompile(`^E:|\bERROR\b`), Style: "fg:red,mod:bold"},
+		{Pattern: regexp.MustCompile(`^W:|\bWARN(ING)?\b`), Style: "fg:yellow"},
+		{Pattern: regexp.MustCompile(`^I:|\bINFO\b`), Style: "fg:green"},
+	}
+}
+
+// ParseColorRule parses "STYLE REGEX", e.g. "fg:red,mod:bold ^E:"
+func ParseColorRule(s string) (ColorRule, error) {
+	s = strings.TrimSpace(s)
+	style, expr, found := strings.Cut(s, " ")
+	expr = strings.TrimSpace(expr)
+	if !found || expr == "" {
+		return ColorRule{}, fmt.Errorf("color rule %q: expected STYLE REGEX", s)
+	}
+	if err := validateStyle(style); err != nil {
+		return ColorRule{}, fmt.Errorf("color rule %q: %v", s, err)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return ColorRule{}, fmt.Errorf("color rule %q: %v", s, err)
+	}
+	return ColorRule{Pattern: re, Style: style}, nil
+}
+
+// LoadColorRules reads rules from the file, one per line. Empty lines and
+// lines starting with # are skipped.
+func LoadColorRules(path string) ([]ColorRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var rules []ColorRule
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := ParseColorRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNum, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// MatchColorRule returns the style of the first rule matching the text, or an
+// empty string.
+func MatchColorRule(rules []ColorRule, text string) string {
+	for _, rule := range rules {
+		if rule.Pattern.MatchString(text) {
+			return rule.Style
+		}
+	}
+	return ""
+}
+
+func validateStyle(style string) error {
+	for _, item := range strings.Split(style, ",") {
+		key, value, _ := strings.Cut(item, ":")
+		var valid []string
+		switch key {
+		case "fg", "bg":
+			valid = styleColors
+		case "mod":
+			valid = styleModifiers
+		default:
+			return fmt.Errorf("invalid style item %q (expected fg:, bg: or mod:)", item)
+		}
+		if !contains(valid, value) {
+			return fmt.Errorf("invalid %s value %q (expected one of %s)", key, value, strings.Join(valid, ", "))
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
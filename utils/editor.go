@@ -0,0 +1,320 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+const MAX_HISTORY_SIZE = 1000
+
+// LineEditor is the input mode line buffer with cursor movement, command
+// history and reverse history search.
+type LineEditor struct {
+	line        []rune
+	cursor      int
+	history     []string
+	historyIdx  int
+	draft       string
+	searching   bool
+	searchQuery string
+	searchIdx   int
+	historyFile string
+	// fileLines is the number of lines in the history file
+	fileLines int
+}
+
+func NewLineEditor() *LineEditor {
+	return &LineEditor{}
+}
+
+// LoadHistory reads the history from the file and remembers it, so committed
+// lines get appended to it. A missing file is not an error.
+func (e *LineEditor) LoadHistory(path string) error {
+	e.historyFile = path
+	lines, err := readHistoryFile(path)
+	e.fileLines = len(lines)
+	e.history = lastLines(lines, MAX_HISTORY_SIZE)
+	e.historyIdx = len(e.history)
+	return err
+}
+
+func (e *LineEditor) Text() string {
+	return string(e.line)
+}
+
+func (e *LineEditor) Searching() bool {
+	return e.searching
+}
+
+func (e *LineEditor) Insert(s string) {
+	if e.searching {
+		e.searchQuery += s
+		if !e.search(len(e.history) - 1) {
+			e.searchIdx = len(e.history)
+		}
+		return
+	}
+	runes := []rune(s)
+	e.line = append(e.line[:e.cursor], append(runes, e.line[e.cursor:]...)...)
+	e.cursor += len(runes)
+}
+
+func (e *LineEditor) Backspace() {
+	if e.searching {
+		if len(e.searchQuery) > 0 {
+			query := []rune(e.searchQuery)
+			e.searchQuery = string(query[:len(query)-1])
+			if !e.search(len(e.history) - 1) {
+				e.searchIdx = len(e.history)
+			}
+		}
+		return
+	}
+	if e.cursor > 0 {
+		e.line = append(e.line[:e.cursor-1], e.line[e.cursor:]...)
+		e.cursor--
+	}
+}
+
+func (e *LineEditor) Delete() {
+	if !e.searching && e.cursor < len(e.line) {
+		e.line = append(e.line[:e.cursor], e.line[e.cursor+1:]...)
+	}
+}
+
+func (e *LineEditor) Left() {
+	e.AcceptSearch()
+	if e.cursor > 0 {
+		e.cursor--
+	}
+}
+
+func (e *LineEditor) Right() {
+	e.AcceptSearch()
+	if e.cursor < len(e.line) {
+		e.cursor++
+	}
+}
+
+func (e *LineEditor) Home() {
+	e.AcceptSearch()
+	e.cursor = 0
+}
+
+func (e *LineEditor) End() {
+	e.AcceptSearch()
+	e.cursor = len(e.line)
+}
+
+// DeleteWord deletes the word before the cursor along with the whitespace
+// following it, like Ctrl-W in a shell.
+func (e *LineEditor) DeleteWord() {
+	e.AcceptSearch()
+	start := e.cursor
+	for start > 0 && unicode.IsSpace(e.line[start-1]) {
+		start--
+	}
+	for start > 0 && !unicode.IsSpace(e.line[start-1]) {
+		start--
+	}
+	e.line = append(e.line[:start], e.line[e.cursor:]...)
+	e.cursor = start
+}
+
+func (e *LineEditor) HistoryPrev() {
+	e.AcceptSearch()
+	if e.historyIdx == 0 {
+		return
+	}
+	if e.historyIdx == len(e.history) {
+		e.draft = string(e.line)
+	}
+	e.historyIdx--
+	e.setLine(e.history[e.historyIdx])
+}
+
+func (e *LineEditor) HistoryNext() {
+	e.AcceptSearch()
+	if e.historyIdx >= len(e.history) {
+		return
+	}
+	e.historyIdx++
+	if e.historyIdx == len(e.history) {
+		e.setLine(e.draft)
+	} else {
+		e.setLine(e.history[e.historyIdx])
+	}
+}
+
+// ReverseSearch starts the reverse history search or, when already searching,
+// jumps to the next older match.
+func (e *LineEditor) ReverseSearch() {
+	if !e.searching {
+		e.searching = true
+		e.searchQuery = ""
+		e.searchIdx = len(e.history)
+		return
+	}
+	e.search(e.searchIdx - 1)
+}
+
+// AcceptSearch ends the search keeping the match in the line.
+func (e *LineEditor) AcceptSearch() {
+	if !e.searching {
+		return
+	}
+	e.searching = false
+	if e.searchIdx < len(e.history) {
+		e.historyIdx = e.searchIdx
+		e.setLine(e.history[e.searchIdx])
+	}
+}
+
+// CancelSearch ends the search keeping the line as it was before.
+func (e *LineEditor) CancelSearch() {
+	e.searching = false
+}
+
+// search looks for the query from the given history position backwards. When
+// nothing is found the current match is kept.
+func (e *LineEditor) search(from int) bool {
+	for i := from; i >= 0; i-- {
+		if strings.Contains(e.history[i], e.searchQuery) {
+			e.searchIdx = i
+			return true
+		}
+	}
+	return false
+}
+
+// Commit clears the line and stores it in the history.
+func (e *LineEditor) Commit() error {
+	line := string(e.line)
+	e.setLine("")
+	e.draft = ""
+	if line != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != line) {
+		e.history = append(e.history, line)
+		if len(e.history) > MAX_HISTORY_SIZE {
+			e.history = e.history[1:]
+		}
+		if err := e.appendToHistoryFile(line); err != nil {
+			e.historyIdx = len(e.history)
+			return err
+		}
+	}
+	e.historyIdx = len(e.history)
+	return nil
+}
+
+func (e *LineEditor) Reset() {
+	e.searching = false
+	e.setLine("")
+	e.historyIdx = len(e.history)
+}
+
+// Render returns the line with the cursor highlighted using termui style
+// syntax, or the search prompt when searching.
+func (e *LineEditor) Render() string {
+	if e.searching {
+		match := ""
+		if e.searchIdx < len(e.history) {
+			match = e.history[e.searchIdx]
+		}
+		return fmt.Sprintf("(reverse-i-search)'%s': %s", e.searchQuery, match)
+	}
+	var builder strings.Builder
+	builder.WriteString(string(e.line[:e.cursor]))
+	cursorChar := " "
+	if e.cursor < len(e.line) {
+		cursorChar = string(e.line[e.cursor])
+	}
+	if strings.ContainsAny(cursorChar, "[]()") {
+		builder.WriteString(cursorChar)
+	} else {
+		builder.WriteString(fmt.Sprintf("[%s](mod:reverse)", cursorChar))
+	}
+	if e.cursor < len(e.line) {
+		builder.WriteString(string(e.line[e.cursor+1:]))
+	}
+	return builder.String()
+}
+
+func (e *LineEditor) setLine(line string) {
+	e.line = []rune(line)
+	e.cursor = len(e.line)
+}
+
+func (e *LineEditor) appendToHistoryFile(line string) error {
+	if e.historyFile == "" {
+		return nil
+	}
+	file, err := os.OpenFile(e.historyFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(file, line)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	e.fileLines++
+	if e.fileLines > MAX_HISTORY_SIZE {
+		return e.truncateHistoryFile()
+	}
+	return nil
+}
+
+// truncateHistoryFile rewrites the history file with its last MAX_HISTORY_SIZE
+// lines. The file is read again rather than rewritten from memory, so lines
+// appended by other running instances are kept.
+func (e *LineEditor) truncateHistoryFile() error {
+	lines, err := readHistoryFile(e.historyFile)
+	if err != nil {
+		return err
+	}
+	lines = lastLines(lines, MAX_HISTORY_SIZE)
+	temp := e.historyFile + ".tmp"
+	if err := os.WriteFile(temp, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(temp, e.historyFile); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	e.fileLines = len(lines)
+	return nil
+}
+
+// readHistoryFile returns the non empty lines of the file, none when the file
+// doesn't exist.
+func readHistoryFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func lastLines(lines []string, count int) []string {
+	if len(lines) > count {
+		return lines[len(lines)-count:]
+	}
+	return lines
+}
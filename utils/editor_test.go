@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLineEditorHistoryFileTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	var lines []string
+	for i := 0; i < MAX_HISTORY_SIZE+10; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	editor := NewLineEditor()
+	if err := editor.LoadHistory(path); err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		editor.Insert(fmt.Sprintf("new %d", i))
+		if err := editor.Commit(); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+	}
+	saved, err := readHistoryFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != MAX_HISTORY_SIZE {
+		t.Fatalf("history file has %d lines, want %d", len(saved), MAX_HISTORY_SIZE)
+	}
+	if saved[0] != "line 13" || saved[len(saved)-1] != "new 2" {
+		t.Errorf("history file spans %q..%q, want \"line 13\"..\"new 2\"", saved[0], saved[len(saved)-1])
+	}
+
+	reloaded := NewLineEditor()
+	if err := reloaded.LoadHistory(path); err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	reloaded.HistoryPrev()
+	if reloaded.Text() != "new 2" {
+		t.Errorf("last history entry = %q, want \"new 2\"", reloaded.Text())
+	}
+}
+
+func TestLineEditorHistoryFileAppended(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	editor := NewLineEditor()
+	if err := editor.LoadHistory(path); err != nil {
+		t.Fatalf("LoadHistory of missing file failed: %v", err)
+	}
+	for _, line := range []string{"a", "b", "b", "c"} {
+		editor.Insert(line)
+		if err := editor.Commit(); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a\nb\nc\n" {
+		t.Errorf("history file = %q, want %q", data, "a\nb\nc\n")
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	UTF8   = "UTF-8"
+	LATIN1 = "LATIN-1"
+	ASCII  = "ASCII"
+)
+
+func GetAvailableEncodings() []string {
+	return []string{UTF8, LATIN1, ASCII}
+}
+
+func ParseEncoding(s string) (string, error) {
+	for _, encoding := range GetAvailableEncodings() {
+		if strings.EqualFold(s, encoding) {
+			return encoding, nil
+		}
+	}
+	return "", fmt.Errorf("invalid text encoding %q", s)
+}
+
+// DecodeText renders raw bytes as text. Invalid UTF-8 is replaced with U+FFFD,
+// Latin-1 maps every byte to the rune of the same value and ASCII escapes
+// control and non-ASCII bytes.
+func DecodeText(data []byte, encoding string) string {
+	switch encoding {
+	case LATIN1:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	case ASCII:
+		return EscapeBytes(data)
+	default:
+		return strings.ToValidUTF8(string(data), "�")
+	}
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UnescapeBytes turns a string with C-like escape sequences (\n, \r, \t, \0,
+// \\ and \xHH) into raw bytes.
+func UnescapeBytes(s string) ([]byte, error) {
+	var out []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			out = append(out, s[i])
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return nil, fmt.Errorf("unterminated escape sequence at position %d", i-1)
+		}
+		switch s[i] {
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 't':
+			out = append(out, '\t')
+		case '0':
+			out = append(out, 0)
+		case '\\':
+			out = append(out, '\\')
+		case 'x':
+			if i+2 >= len(s) {
+				return nil, fmt.Errorf("incomplete \\x escape at position %d", i-1)
+			}
+			b, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid \\x escape %q at position %d", s[i-1:i+3], i-1)
+			}
+			out = append(out, byte(b))
+			i += 2
+		default:
+			return nil, fmt.Errorf("unknown escape sequence \\%c at position %d", s[i], i-1)
+		}
+	}
+	return out, nil
+}
+
+// EscapeBytes is the inverse of UnescapeBytes, printable ASCII is kept as is.
+func EscapeBytes(b []byte) string {
+	var builder strings.Builder
+	for _, c := range b {
+		switch {
+		case c == '\n':
+			builder.WriteString(`\n`)
+		case c == '\r':
+			builder.WriteString(`\r`)
+		case c == '\t':
+			builder.WriteString(`\t`)
+		case c == 0:
+			builder.WriteString(`\0`)
+		case c == '\\':
+			builder.WriteString(`\\`)
+		case c < 0x20 || c > 0x7e:
+			builder.WriteString(fmt.Sprintf(`\x%02X`, c))
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+const (
+	EOL_NONE = "none"
+	EOL_LF   = "lf"
+	EOL_CR   = "cr"
+	EOL_CRLF = "crlf"
+)
+
+var eolBytes = map[string][]byte{
+	EOL_NONE: nil,
+	EOL_LF:   []byte("\n"),
+	EOL_CR:   []byte("\r"),
+	EOL_CRLF: []byte("\r\n"),
+}
+
+func GetAvailableEols() []string {
+	return []string{EOL_NONE, EOL_LF, EOL_CR, EOL_CRLF}
+}
+
+func ParseEol(s string) (string, error) {
+	eol := strings.ToLower(s)
+	if _, ok := eolBytes[eol]; !ok {
+		return "", fmt.Errorf("invalid line ending %q, expected none, lf, cr or crlf", s)
+	}
+	return eol, nil
+}
+
+// EolBytes returns the bytes of the line ending returned by ParseEol.
+func EolBytes(eol string) []byte {
+	return eolBytes[eol]
+}
+
+// ParseHexBytes parses hex bytes separated by whitespace like "AA 55 01 FF".
+// Separators are optional, so "AA5501FF" is accepted as well.
+func ParseHexBytes(s string) ([]byte, error) {
+	var out []byte
+	for _, token := range strings.Fields(s) {
+		if len(token)%2 != 0 {
+			return nil, fmt.Errorf("odd number of hex digits in %q", token)
+		}
+		for i := 0; i < len(token); i += 2 {
+			b, err := strconv.ParseUint(token[i:i+2], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hex byte %q", token[i:i+2])
+			}
+			out = append(out, byte(b))
+		}
+	}
+	return out, nil
+}
+
+// FormatHexBytes is the inverse of ParseHexBytes, e.g. "AA 55 01 FF"
+func FormatHexBytes(b []byte) string {
+	hexBytes := make([]string, len(b))
+	for i, c := range b {
+		hexBytes[i] = fmt.Sprintf("%02X", c)
+	}
+	return strings.Join(hexBytes, " ")
+}
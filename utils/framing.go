@@ -0,0 +1,240 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DELIMITER_FRAMING = "delim"
+	FIXED_FRAMING     = "fixed"
+	IDLE_FRAMING      = "idle"
+	COBS_FRAMING      = "cobs"
+	SLIP_FRAMING      = "slip"
+
+	DEFAULT_FRAMING = `delim:\n`
+)
+
+// Framer splits the received byte stream into frames.
+type Framer interface {
+	// Push consumes received bytes and returns the frames completed by them.
+	Push(data []byte) [][]byte
+	// Flush returns the incomplete frame (if any) and resets the framer.
+	Flush() []byte
+	// IdleTimeout returns the gap after which pending data should be flushed
+	// as a frame, 0 if the framer doesn't end frames on idle line.
+	IdleTimeout() time.Duration
+	// String returns the spec the framer has been created from.
+	String() string
+}
+
+// DecodingFramer is a framer for binary packet framings, which decodes frame
+// payloads and counts the frames it failed to decode.
+type DecodingFramer interface {
+	Framer
+	// Encode wraps the payload in the same framing.
+	Encode(payload []byte) []byte
+	// Errors returns the number of malformed frames dropped so far.
+	Errors() int
+}
+
+// ParseFraming creates a framer from one of the specs:
+// delim:SEQ (SEQ may contain escapes like \r\n, \0 or \x03), fixed:LEN,
+// idle:MS, cobs or slip.
+func ParseFraming(spec string) (Framer, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch strings.ToLower(kind) {
+	case COBS_FRAMING:
+		return &decodingFramer{name: COBS_FRAMING, delimiterFramer: delimiterFramer{delimiter: []byte{0}}, decode: CobsDecode, encode: CobsEncode}, nil
+	case SLIP_FRAMING:
+		return &decodingFramer{name: SLIP_FRAMING, delimiterFramer: delimiterFramer{delimiter: []byte{SLIP_END}}, decode: SlipDecode, encode: SlipEncode}, nil
+	}
+	if arg == "" {
+		return nil, fmt.Errorf("invalid framing %q, expected delim:SEQ, fixed:LEN, idle:MS, cobs or slip", spec)
+	}
+	switch strings.ToLower(kind) {
+	case DELIMITER_FRAMING:
+		delimiter, err := UnescapeBytes(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid framing delimiter %q: %w", arg, err)
+		}
+		return &delimiterFramer{delimiter: delimiter}, nil
+	case FIXED_FRAMING:
+		length, err := strconv.Atoi(arg)
+		if err != nil || length <= 0 {
+			return nil, fmt.Errorf("invalid fixed frame length %q", arg)
+		}
+		return &fixedFramer{length: length}, nil
+	case IDLE_FRAMING:
+		millis, err := strconv.Atoi(arg)
+		if err != nil || millis <= 0 {
+			return nil, fmt.Errorf("invalid idle gap %q", arg)
+		}
+		return &idleFramer{gap: time.Duration(millis) * time.Millisecond}, nil
+	}
+	return nil, fmt.Errorf("unknown framing %q", kind)
+}
+
+// PacketEncoder returns the stateless encoder of a binary packet framing spec
+// (cobs, slip), nil for framings which send the payload as it is.
+func PacketEncoder(spec string) func(payload []byte) []byte {
+	switch strings.ToLower(spec) {
+	case COBS_FRAMING:
+		return CobsEncode
+	case SLIP_FRAMING:
+		return SlipEncode
+	}
+	return nil
+}
+
+type delimiterFramer struct {
+	delimiter []byte
+	buff      bytes.Buffer
+}
+
+func (f *delimiterFramer) Push(data []byte) [][]byte {
+	f.buff.Write(data)
+	var frames [][]byte
+	for {
+		i := bytes.Index(f.buff.Bytes(), f.delimiter)
+		if i < 0 {
+			break
+		}
+		frame := make([]byte, i)
+		copy(frame, f.buff.Next(i))
+		f.buff.Next(len(f.delimiter))
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func (f *delimiterFramer) Flush() []byte {
+	return flushBuffer(&f.buff)
+}
+
+func (f *delimiterFramer) IdleTimeout() time.Duration {
+	return 0
+}
+
+func (f *delimiterFramer) String() string {
+	return fmt.Sprintf("%s:%s", DELIMITER_FRAMING, EscapeBytes(f.delimiter))
+}
+
+type fixedFramer struct {
+	length int
+	buff   bytes.Buffer
+}
+
+func (f *fixedFramer) Push(data []byte) [][]byte {
+	f.buff.Write(data)
+	var frames [][]byte
+	for f.buff.Len() >= f.length {
+		frame := make([]byte, f.length)
+		copy(frame, f.buff.Next(f.length))
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func (f *fixedFramer) Flush() []byte {
+	return flushBuffer(&f.buff)
+}
+
+func (f *fixedFramer) IdleTimeout() time.Duration {
+	return 0
+}
+
+func (f *fixedFramer) String() string {
+	return fmt.Sprintf("%s:%d", FIXED_FRAMING, f.length)
+}
+
+type idleFramer struct {
+	gap  time.Duration
+	buff bytes.Buffer
+}
+
+func (f *idleFramer) Push(data []byte) [][]byte {
+	f.buff.Write(data)
+	return nil
+}
+
+func (f *idleFramer) Flush() []byte {
+	return flushBuffer(&f.buff)
+}
+
+func (f *idleFramer) IdleTimeout() time.Duration {
+	return f.gap
+}
+
+func (f *idleFramer) String() string {
+	return fmt.Sprintf("%s:%d", IDLE_FRAMING, f.gap.Milliseconds())
+}
+
+type decodingFramer struct {
+	delimiterFramer
+	name   string
+	decode func([]byte) ([]byte, error)
+	encode func([]byte) []byte
+	errors int
+}
+
+func (f *decodingFramer) Push(data []byte) [][]byte {
+	var frames [][]byte
+	for _, raw := range f.delimiterFramer.Push(data) {
+		// back to back delimiters are used to resynchronize, not as empty packets
+		if len(raw) == 0 {
+			continue
+		}
+		frame, err := f.decode(raw)
+		if err != nil {
+			f.errors++
+			continue
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// Flush drops the incomplete frame, as it cannot be decoded.
+func (f *decodingFramer) Flush() []byte {
+	f.delimiterFramer.Flush()
+	return nil
+}
+
+func (f *decodingFramer) Encode(payload []byte) []byte {
+	return f.encode(payload)
+}
+
+func (f *decodingFramer) Errors() int {
+	return f.errors
+}
+
+func (f *decodingFramer) String() string {
+	return f.name
+}
+
+// FrameBytes returns the bytes the framer splits into the payload, used to feed
+// recorded frames back through a framer. Frames of idle framing are only
+// separated by the timing.
+func FrameBytes(f Framer, payload []byte) []byte {
+	switch framer := f.(type) {
+	case DecodingFramer:
+		return framer.Encode(payload)
+	case *delimiterFramer:
+		return append(append([]byte{}, payload...), framer.delimiter...)
+	}
+	return payload
+}
+
+func flushBuffer(buff *bytes.Buffer) []byte {
+	if buff.Len() == 0 {
+		return nil
+	}
+	frame := make([]byte, buff.Len())
+	copy(frame, buff.Bytes())
+	buff.Reset()
+	return frame
+}
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFraming(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		timeout time.Duration
+	}{
+		{`delim:\n`, `delim:\n`, 0},
+		{`DELIM:\r\n`, `delim:\r\n`, 0},
+		{`delim:\x03`, `delim:\x03`, 0},
+		{"fixed:8", "fixed:8", 0},
+		{"idle:50", "idle:50", 50 * time.Millisecond},
+		{"cobs", "cobs", 0},
+		{"SLIP", "slip", 0},
+	}
+	for _, tt := range tests {
+		framer, err := ParseFraming(tt.spec)
+		if err != nil {
+			t.Errorf("ParseFraming(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if framer.String() != tt.want {
+			t.Errorf("ParseFraming(%q).String() = %q, want %q", tt.spec, framer.String(), tt.want)
+		}
+		if framer.IdleTimeout() != tt.timeout {
+			t.Errorf("ParseFraming(%q).IdleTimeout() = %v, want %v", tt.spec, framer.IdleTimeout(), tt.timeout)
+		}
+	}
+}
+
+func TestParseFramingInvalid(t *testing.T) {
+	for _, spec := range []string{"", "delim", "delim:", `delim:\q`, "fixed:0", "fixed:-1", "fixed:x", "idle:0", "idle:x", "line:\n"} {
+		if framer, err := ParseFraming(spec); err == nil {
+			t.Errorf("ParseFraming(%q) = %v, want error", spec, framer)
+		}
+	}
+}
+
+func TestFramerPush(t *testing.T) {
+	tests := []struct {
+		spec   string
+		chunks []string
+		frames []string
+		rest   string
+	}{
+		{`delim:\n`, []string{"ab\ncd", "\n\nef"}, []string{"ab", "cd", ""}, "ef"},
+		{`delim:\r\n`, []string{"ab\r", "\ncd\r", "\r\n"}, []string{"ab", "cd\r"}, ""},
+		{"fixed:3", []string{"ab", "cdefg", "hi"}, []string{"abc", "def", "ghi"}, ""},
+		{"fixed:2", []string{"abc"}, []string{"ab"}, "c"},
+		{"idle:10", []string{"ab\n", "cd"}, nil, "ab\ncd"},
+		{"cobs", []string{"\x03ab\x00\x01", "\x00"}, []string{"ab", ""}, ""},
+		{"slip", []string{"\xC0ab\xC0\xC0c\xDB", "\xDCd"}, []string{"ab"}, ""},
+	}
+	for _, tt := range tests {
+		framer, err := ParseFraming(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseFraming(%q) failed: %v", tt.spec, err)
+		}
+		var frames []string
+		for _, chunk := range tt.chunks {
+			for _, frame := range framer.Push([]byte(chunk)) {
+				frames = append(frames, string(frame))
+			}
+		}
+		if !reflect.DeepEqual(frames, tt.frames) {
+			t.Errorf("%s: frames = %q, want %q", tt.spec, frames, tt.frames)
+		}
+		if rest := string(framer.Flush()); rest != tt.rest {
+			t.Errorf("%s: Flush() = %q, want %q", tt.spec, rest, tt.rest)
+		}
+		if rest := framer.Flush(); rest != nil {
+			t.Errorf("%s: second Flush() = %q, want nil", tt.spec, rest)
+		}
+	}
+}
+
+func TestDecodingFramerErrors(t *testing.T) {
+	tests := []struct {
+		spec   string
+		data   []byte
+		frames []string
+		errors int
+	}{
+		{"cobs", concat(CobsEncode([]byte("a")), []byte{0x05, 0x01, 0x00}, CobsEncode([]byte("b"))), []string{"a", "b"}, 1},
+		{"cobs", []byte{0x00, 0x00, 0x02, 0x00, 0x00}, nil, 1},
+		{"slip", concat(SlipEncode([]byte("a")), []byte{SLIP_ESC, 0x01, SLIP_END}, []byte{SLIP_ESC, SLIP_END}, SlipEncode([]byte("b"))), []string{"a", "b"}, 2},
+		{"slip", []byte{SLIP_END, SLIP_END, SLIP_END}, nil, 0},
+	}
+	for _, tt := range tests {
+		framer, err := ParseFraming(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseFraming(%q) failed: %v", tt.spec, err)
+		}
+		decoder := framer.(DecodingFramer)
+		var frames []string
+		for _, frame := range decoder.Push(tt.data) {
+			frames = append(frames, string(frame))
+		}
+		if !reflect.DeepEqual(frames, tt.frames) {
+			t.Errorf("%s % X: frames = %q, want %q", tt.spec, tt.data, frames, tt.frames)
+		}
+		if decoder.Errors() != tt.errors {
+			t.Errorf("%s % X: Errors() = %d, want %d", tt.spec, tt.data, decoder.Errors(), tt.errors)
+		}
+	}
+}
+
+func TestFrameBytes(t *testing.T) {
+	payload := []byte("a\x00b")
+	for _, spec := range []string{`delim:\r\n`, "fixed:3", "cobs", "slip"} {
+		framer, err := ParseFraming(spec)
+		if err != nil {
+			t.Fatalf("ParseFraming(%q) failed: %v", spec, err)
+		}
+		frames := framer.Push(FrameBytes(framer, payload))
+		if len(frames) != 1 || !bytes.Equal(frames[0], payload) {
+			t.Errorf("%s: framing FrameBytes(% X) gives %q", spec, payload, frames)
+		}
+	}
+	idle, _ := ParseFraming("idle:10")
+	if got := FrameBytes(idle, payload); !bytes.Equal(got, payload) {
+		t.Errorf("idle: FrameBytes(% X) = % X", payload, got)
+	}
+}
+
+func TestPacketEncoder(t *testing.T) {
+	payload := []byte{0x00, SLIP_END}
+	if encode := PacketEncoder("cobs"); encode == nil || !bytes.Equal(encode(payload), CobsEncode(payload)) {
+		t.Errorf("PacketEncoder(cobs) doesn't encode with COBS")
+	}
+	if encode := PacketEncoder("slip"); encode == nil || !bytes.Equal(encode(payload), SlipEncode(payload)) {
+		t.Errorf("PacketEncoder(slip) doesn't encode with SLIP")
+	}
+	for _, spec := range []string{`delim:\n`, "fixed:3", "idle:10"} {
+		if PacketEncoder(spec) != nil {
+			t.Errorf("PacketEncoder(%q) != nil", spec)
+		}
+	}
+}
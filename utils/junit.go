@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"time"
+)
+
+type JUnitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []JUnitTestSuite `xml:"testsuite"`
+}
+
+type JUnitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []JUnitTestCase `xml:"testcase"`
+}
+
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// NewJUnitTestSuite sums up the cases into a suite
+func NewJUnitTestSuite(name string, started time.Time, duration time.Duration, cases []JUnitTestCase) JUnitTestSuite {
+	suite := JUnitTestSuite{
+		Name:      name,
+		Tests:     len(cases),
+		Time:      FormatJUnitTime(duration),
+		Timestamp: started.Format("2006-01-02T15:04:05"),
+		Cases:     cases,
+	}
+	for _, c := range cases {
+		if c.Failure != nil {
+			suite.Failures++
+		}
+		if c.Skipped != nil {
+			suite.Skipped++
+		}
+	}
+	return suite
+}
+
+func FormatJUnitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func WriteJUnit(path string, suites ...JUnitTestSuite) error {
+	data, err := xml.MarshalIndent(JUnitTestSuites{Suites: suites}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0666)
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"go.bug.st/serial"
+)
+
+var parityNames = map[serial.Parity]string{
+	serial.NoParity:    "N",
+	serial.OddParity:   "O",
+	serial.EvenParity:  "E",
+	serial.MarkParity:  "M",
+	serial.SpaceParity: "S",
+}
+
+var stopBitsNames = map[serial.StopBits]string{
+	serial.OneStopBit:           "1",
+	serial.OnePointFiveStopBits: "1.5",
+	serial.TwoStopBits:          "2",
+}
+
+func ParseDataBits(dataBits int) (int, error) {
+	if dataBits < 5 || dataBits > 8 {
+		return 0, fmt.Errorf("data bits must be 5, 6, 7 or 8, got %d", dataBits)
+	}
+	return dataBits, nil
+}
+
+// ParseParity accepts both the full name (none, odd, even, mark, space) and the
+// single letter used in the compact frame notation (N, O, E, M, S).
+func ParseParity(s string) (serial.Parity, error) {
+	switch strings.ToLower(s) {
+	case "n", "none":
+		return serial.NoParity, nil
+	case "o", "odd":
+		return serial.OddParity, nil
+	case "e", "even":
+		return serial.EvenParity, nil
+	case "m", "mark":
+		return serial.MarkParity, nil
+	case "s", "space":
+		return serial.SpaceParity, nil
+	}
+	return serial.NoParity, fmt.Errorf("invalid parity: %q", s)
+}
+
+func ParseStopBits(s string) (serial.StopBits, error) {
+	for stopBits, name := range stopBitsNames {
+		if s == name {
+			return stopBits, nil
+		}
+	}
+	return serial.OneStopBit, fmt.Errorf("invalid stop bits: %q", s)
+}
+
+// ParseLineFrame parses the compact notation like 8N1, 7E1 or 8N1.5 into the
+// serial mode fields.
+func ParseLineFrame(frame string) (int, serial.Parity, serial.StopBits, error) {
+	if len(frame) < 3 {
+		return 0, serial.NoParity, serial.OneStopBit, fmt.Errorf("invalid frame: %q", frame)
+	}
+	dataBits, err := ParseDataBits(int(frame[0] - '0'))
+	if err != nil {
+		return 0, serial.NoParity, serial.OneStopBit, fmt.Errorf("invalid frame %q: %w", frame, err)
+	}
+	parity, err := ParseParity(frame[1:2])
+	if err != nil {
+		return 0, serial.NoParity, serial.OneStopBit, fmt.Errorf("invalid frame %q: %w", frame, err)
+	}
+	stopBits, err := ParseStopBits(frame[2:])
+	if err != nil {
+		return 0, serial.NoParity, serial.OneStopBit, fmt.Errorf("invalid frame %q: %w", frame, err)
+	}
+	return dataBits, parity, stopBits, nil
+}
+
+func FormatLineFrame(mode *serial.Mode) string {
+	return fmt.Sprintf("%d%s%s", mode.DataBits, parityNames[mode.Parity], stopBitsNames[mode.StopBits])
+}
@@ -1,15 +1,40 @@
 package utils
 
-import "time"
+import (
+	"sync/atomic"
+	"time"
+)
+
+type Direction int
+
+const (
+	RX Direction = iota
+	TX
+)
+
+func (d Direction) String() string {
+	if d == TX {
+		return "TX"
+	}
+	return "RX"
+}
+
+var lastSeq atomic.Uint64
 
 type Message struct {
 	Timestamp time.Time
-	Content   any
+	Direction Direction
+	Port      string
+	Seq       uint64
+	Data      []byte
 }
 
-func NowMessage(msg any) *Message {
+func NewMessage(direction Direction, port string, data []byte) *Message {
 	return &Message{
 		Timestamp: time.Now(),
-		Content:   msg,
+		Direction: direction,
+		Port:      port,
+		Seq:       lastSeq.Add(1),
+		Data:      data,
 	}
 }
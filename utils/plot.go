@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PlotValue struct {
+	Label string
+	Value float64
+}
+
+// Series holds the samples of a single plotted value in chronological order.
+type Series struct {
+	Label      string
+	Values     []float64
+	Timestamps []time.Time
+	// Lines holds the position of the line of each sample among the queried
+	// lines (0 is the oldest), so series missing from some lines stay aligned.
+	Lines []int
+}
+
+// ParsePlotLine parses a line in the Arduino Serial Plotter format. Values
+// are separated by commas, spaces or tabs and may be labeled like temp:21.5.
+// Unlabeled values are named after their position among the values. Lines
+// with tokens which are not numbers are text, not plot data, so nothing is
+// returned for them. Values which are not finite (nan printed on a failed
+// sensor read, inf) are skipped.
+func ParsePlotLine(line string) []PlotValue {
+	tokens := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\r' || r == '\n'
+	})
+	var values []PlotValue
+	for i, token := range tokens {
+		label, valueStr, labeled := strings.Cut(token, ":")
+		if !labeled {
+			label = fmt.Sprintf("value %d", i+1)
+			valueStr = token
+		}
+		value, err := strconv.ParseFloat(valueStr, 64)
+		if err != nil {
+			return nil
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+		values = append(values, PlotValue{Label: label, Value: value})
+	}
+	return values
+}
+
+// SeriesQuery selects the received messages to be plotted.
+type SeriesQuery struct {
+	// MaxLines limits the number of lines holding plot values
+	MaxLines int
+	// Since and Until limit the message timestamps, zero means no limit
+	Since time.Time
+	Until time.Time
+	// SkipLines skips the given number of newest lines, used to pan back
+	SkipLines int
+}
+
+// ListToSeries parses the newest received messages holding plot values into
+// series, ordered by the first appearance of their label.
+func ListToSeries(l *list.List, query SeriesQuery) []*Series {
+	type line struct {
+		timestamp time.Time
+		values    []PlotValue
+	}
+	var lines []line
+	skipped := 0
+	for e := l.Front(); e != nil && len(lines) < query.MaxLines; e = e.Next() {
+		msg := e.Value.(*Message)
+		if !query.Since.IsZero() && msg.Timestamp.Before(query.Since) {
+			break
+		}
+		if msg.Direction != RX || (!query.Until.IsZero() && msg.Timestamp.After(query.Until)) {
+			continue
+		}
+		values := ParsePlotLine(string(msg.Data))
+		if len(values) == 0 {
+			continue
+		}
+		if skipped < query.SkipLines {
+			skipped++
+			continue
+		}
+		lines = append(lines, line{timestamp: msg.Timestamp, values: values})
+	}
+	var series []*Series
+	byLabel := make(map[string]*Series)
+	for i := len(lines) - 1; i >= 0; i-- {
+		for _, value := range lines[i].values {
+			s, ok := byLabel[value.Label]
+			if !ok {
+				s = &Series{Label: value.Label}
+				byLabel[value.Label] = s
+				series = append(series, s)
+			}
+			s.Values = append(s.Values, value.Value)
+			s.Timestamps = append(s.Timestamps, lines[i].timestamp)
+			s.Lines = append(s.Lines, len(lines)-1-i)
+		}
+	}
+	return series
+}
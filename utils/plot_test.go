@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"container/list"
+	"reflect"
+	"testing"
+)
+
+func TestParsePlotLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want []PlotValue
+	}{
+		{"1.2,3.4,5.6\r\n", []PlotValue{{"value 1", 1.2}, {"value 2", 3.4}, {"value 3", 5.6}}},
+		{"temp:21.5 hum:40", []PlotValue{{"temp", 21.5}, {"hum", 40}}},
+		{"1\t-2", []PlotValue{{"value 1", 1}, {"value 2", -2}}},
+		{"temp:21.5 hum:nan", []PlotValue{{"temp", 21.5}}},
+		{"nan,2", []PlotValue{{"value 2", 2}}},
+		{"inf -Inf NaN", nil},
+		{"Temp is 21.5 C at 3", nil},
+		{"temp:warm", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := ParsePlotLine(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParsePlotLine(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestListToSeriesAlignsLines(t *testing.T) {
+	messages := list.New()
+	for _, line := range []string{"temp:20 hum:40", "temp:21 hum:41 err:1", "text line", "temp:22 hum:42", "temp:23 hum:43 err:2"} {
+		// the list holds the newest message at the front
+		messages.PushFront(NewMessage(RX, "port", []byte(line)))
+	}
+	series := ListToSeries(messages, SeriesQuery{MaxLines: 10})
+	want := map[string][]int{"temp": {0, 1, 2, 3}, "hum": {0, 1, 2, 3}, "err": {1, 3}}
+	if len(series) != len(want) {
+		t.Fatalf("got %d series, want %d", len(series), len(want))
+	}
+	for _, s := range series {
+		if !reflect.DeepEqual(s.Lines, want[s.Label]) {
+			t.Errorf("%s lines = %v, want %v", s.Label, s.Lines, want[s.Label])
+		}
+	}
+}
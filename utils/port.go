@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"go.bug.st/serial/enumerator"
+)
+
+// PortMatcher identifies an USB serial adapter by its vendor id, product id and
+// (optionally) serial number.
+type PortMatcher struct {
+	VID          string
+	PID          string
+	SerialNumber string
+}
+
+// ParsePortMatcher parses the vid:pid[:serial] notation. VID and PID are
+// compared case-insensitively as they are reported in hex.
+func ParsePortMatcher(s string) (*PortMatcher, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid port match %q, expected vid:pid[:serial]", s)
+	}
+	matcher := &PortMatcher{VID: parts[0], PID: parts[1]}
+	if len(parts) == 3 {
+		matcher.SerialNumber = parts[2]
+	}
+	return matcher, nil
+}
+
+func (m *PortMatcher) Matches(port *enumerator.PortDetails) bool {
+	if !port.IsUSB {
+		return false
+	}
+	if !strings.EqualFold(m.VID, port.VID) || !strings.EqualFold(m.PID, port.PID) {
+		return false
+	}
+	return m.SerialNumber == "" || m.SerialNumber == port.SerialNumber
+}
+
+func (m *PortMatcher) String() string {
+	if m.SerialNumber == "" {
+		return fmt.Sprintf("%s:%s", m.VID, m.PID)
+	}
+	return fmt.Sprintf("%s:%s:%s", m.VID, m.PID, m.SerialNumber)
+}
+
+func FormatPortDetails(port *enumerator.PortDetails) string {
+	if !port.IsUSB {
+		return port.Name
+	}
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%s [%s:%s]", port.Name, port.VID, port.PID))
+	if port.SerialNumber != "" {
+		builder.WriteString(fmt.Sprintf(" serial=%s", port.SerialNumber))
+	}
+	if port.Product != "" {
+		builder.WriteString(fmt.Sprintf(" %s", port.Product))
+	}
+	return builder.String()
+}
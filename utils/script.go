@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	STEP_SEND    = "send"
+	STEP_EXPECT  = "expect"
+	STEP_SLEEP   = "sleep"
+	STEP_CAPTURE = "capture"
+
+	WITHIN_KEYWORD = "within"
+)
+
+var scriptVariable = regexp.MustCompile(`\$\{(\w+)\}`)
+var variableName = regexp.MustCompile(`^\w+$`)
+
+// ScriptStep is a single line of a test script:
+//
+//	send "AT\r"                       data with escapes, ${var} is substituted
+//	expect /OK/ within 500ms          waits for a message matching the regex
+//	sleep 1s
+//	capture var /ID=(\d+)/ within 1s  stores the first group (or the match)
+type ScriptStep struct {
+	Line    int
+	Text    string
+	Kind    string
+	Data    string
+	Pattern *regexp.Regexp
+	// Timeout of expect and capture, 0 - default timeout
+	Timeout  time.Duration
+	Duration time.Duration
+	Variable string
+}
+
+func (s ScriptStep) String() string {
+	return s.Text
+}
+
+// SendData returns the data to send with the variables substituted
+func (s ScriptStep) SendData(vars map[string]string) ([]byte, error) {
+	var missing []string
+	data := scriptVariable.ReplaceAllStringFunc(s.Data, func(ref string) string {
+		name := scriptVariable.FindStringSubmatch(ref)[1]
+		value, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("undefined variables: %s", strings.Join(missing, ", "))
+	}
+	return UnescapeBytes(data)
+}
+
+// Capture returns the captured value if the text matches the pattern
+func (s ScriptStep) Capture(text string) (string, bool) {
+	match := s.Pattern.FindStringSubmatch(text)
+	if match == nil {
+		return "", false
+	}
+	if len(match) > 1 {
+		return match[1], true
+	}
+	return match[0], true
+}
+
+// LoadScript reads the script steps, empty lines and lines starting with #
+// are skipped.
+func LoadScript(path string) ([]ScriptStep, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var steps []ScriptStep
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		step, err := ParseScriptStep(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNum, err)
+		}
+		step.Line = lineNum
+		steps = append(steps, step)
+	}
+	return steps, scanner.Err()
+}
+
+func ParseScriptStep(line string) (ScriptStep, error) {
+	kind, args, _ := strings.Cut(line, " ")
+	args = strings.TrimSpace(args)
+	step := ScriptStep{Text: line, Kind: strings.ToLower(kind)}
+	var err error
+	switch step.Kind {
+	case STEP_SEND:
+		step.Data, err = parseQuoted(args)
+		if err == nil {
+			// variables are known only when running, check just the escapes
+			_, err = UnescapeBytes(scriptVariable.ReplaceAllString(step.Data, ""))
+		}
+	case STEP_SLEEP:
+		step.Duration, err = time.ParseDuration(args)
+	case STEP_EXPECT:
+		step.Pattern, step.Timeout, err = parsePattern(args)
+	case STEP_CAPTURE:
+		step.Variable, args, _ = strings.Cut(args, " ")
+		if !variableName.MatchString(step.Variable) {
+			return step, fmt.Errorf("invalid variable name %q", step.Variable)
+		}
+		step.Pattern, step.Timeout, err = parsePattern(strings.TrimSpace(args))
+	default:
+		return step, fmt.Errorf("unknown step %q, expected send, expect, sleep or capture", kind)
+	}
+	return step, err
+}
+
+// parseQuoted returns the text between the first and the last double quote
+func parseQuoted(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("expected quoted data, e.g. \"AT\\r\"")
+	}
+	return s[1 : len(s)-1], nil
+}
+
+// parsePattern parses "/REGEX/ [within DURATION]"
+func parsePattern(s string) (*regexp.Regexp, time.Duration, error) {
+	end := strings.LastIndex(s, "/")
+	if !strings.HasPrefix(s, "/") || end == 0 {
+		return nil, 0, fmt.Errorf("expected /regex/")
+	}
+	re, err := regexp.Compile(s[1:end])
+	if err != nil {
+		return nil, 0, err
+	}
+	rest := strings.Fields(s[end+1:])
+	if len(rest) == 0 {
+		return re, 0, nil
+	}
+	if len(rest) != 2 || rest[0] != WITHIN_KEYWORD {
+		return nil, 0, fmt.Errorf("expected %s DURATION after the regex", WITHIN_KEYWORD)
+	}
+	timeout, err := time.ParseDuration(rest[1])
+	if err != nil {
+		return nil, 0, err
+	}
+	return re, timeout, nil
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"math"
+	"time"
+)
+
+type SeriesStats struct {
+	Label  string
+	Count  int
+	Min    float64
+	Max    float64
+	Mean   float64
+	StdDev float64
+	Last   float64
+	RateHz float64
+}
+
+// ComputeStats summarizes the samples of the series taken after since.
+func ComputeStats(s *Series, since time.Time) SeriesStats {
+	stats := SeriesStats{Label: s.Label, Min: math.Inf(1), Max: math.Inf(-1)}
+	var sum, sumSquares float64
+	var first, last time.Time
+	for i, value := range s.Values {
+		timestamp := s.Timestamps[i]
+		if timestamp.Before(since) {
+			continue
+		}
+		if stats.Count == 0 {
+			first = timestamp
+		}
+		last = timestamp
+		stats.Count++
+		stats.Min = math.Min(stats.Min, value)
+		stats.Max = math.Max(stats.Max, value)
+		stats.Last = value
+		sum += value
+		sumSquares += value * value
+	}
+	if stats.Count == 0 {
+		stats.Min, stats.Max = math.NaN(), math.NaN()
+		stats.Mean, stats.StdDev, stats.Last = math.NaN(), math.NaN(), math.NaN()
+		return stats
+	}
+	n := float64(stats.Count)
+	stats.Mean = sum / n
+	// population standard deviation, clamped as rounding can make the variance slightly negative
+	stats.StdDev = math.Sqrt(math.Max(sumSquares/n-stats.Mean*stats.Mean, 0))
+	if elapsed := last.Sub(first).Seconds(); stats.Count > 1 && elapsed > 0 {
+		stats.RateHz = (n - 1) / elapsed
+	}
+	return stats
+}
@@ -6,8 +6,9 @@ import (
 	"fmt"
 	"log"
 	"math"
-	"strconv"
+	"regexp"
 	"strings"
+	"time"
 )
 
 const TIME_FORMAT = "15:04:05.000000"
@@ -23,59 +24,171 @@ func MustFn(description string, fn func() error) {
 	Must(description, fn())
 }
 
-func ListToSliceMsg(l *list.List, maxLen int, printTime bool, printInHex bool) []string {
+const (
+	RX_TAG = "[<<](fg:green)"
+	TX_TAG = "[>>](fg:cyan,mod:bold)"
+)
+
+type MsgRenderOptions struct {
+	PrintTime  bool
+	PrintInHex bool
+	Encoding   string
+	// ShowDirection tags rows with << (received) or >> (sent) and annotates
+	// the first response after a sent message with the latency. Without it
+	// sent messages are left out, as they would look like received ones.
+	ShowDirection bool
+	// Filter shows only messages matching it (or not matching it when
+	// InvertFilter is set), nil shows all messages.
+	Filter       *regexp.Regexp
+	InvertFilter bool
+	// Highlight marks its matches in the message text.
+	Highlight *regexp.Regexp
+	// ColorRules style the text of matching messages, first match wins.
+	ColorRules []ColorRule
+	// RenderAnsi translates ANSI SGR sequences to styles, otherwise escapes
+	// are shown raw.
+	RenderAnsi bool
+}
+
+const HIGHLIGHT_STYLE = "fg:black,bg:yellow"
+
+// ListToSliceMsg renders messages as list rows, along with indexes of the
+// rows of messages matching opts.Highlight.
+func ListToSliceMsg(l *list.List, maxLen int, opts MsgRenderOptions) ([]string, []int) {
 	var arr []string
+	var matchedRows []int
+	var latencies map[*Message]time.Duration
+	if opts.ShowDirection {
+		latencies = responseLatencies(l)
+	}
 	i := 0
 	for e := l.Back(); e != nil && i < maxLen; e = e.Prev() {
 		msg := e.Value.(*Message)
+		if msg.Direction == TX && !opts.ShowDirection {
+			continue
+		}
+		spans := messageSpans(msg, opts)
+		text := spansText(spans)
+		if opts.Filter != nil && opts.Filter.MatchString(text) == opts.InvertFilter {
+			continue
+		}
+		var matches [][]int
+		if opts.Highlight != nil {
+			matches = opts.Highlight.FindAllStringIndex(text, -1)
+		}
+		if len(matches) > 0 {
+			matchedRows = append(matchedRows, len(arr))
+		}
+		text = renderSpans(spans, MatchColorRule(opts.ColorRules, text), matches)
 		var prefix string
-		if printTime {
+		if opts.PrintTime {
 			prefix = fmt.Sprintf("[%s]:", msg.Timestamp.Format(TIME_FORMAT))
 		} else {
 			prefix = fmt.Sprintf("[%d]:", i+1)
 		}
-		switch t := msg.Content.(type) {
-		case string:
-			if printInHex {
-				arr = append(arr, append([]string{fmt.Sprintf("%s %s", prefix, strings.TrimRight(msg.Content.(string), "\r\n"))}, toHexLines(msg.Content.(string))...)...)
+		if opts.ShowDirection {
+			if msg.Direction == TX {
+				prefix = fmt.Sprintf("%s %s", prefix, TX_TAG)
 			} else {
-				arr = append(arr, fmt.Sprintf("%s %s", prefix, strings.TrimRight(msg.Content.(string), "\r\n")))
+				prefix = fmt.Sprintf("%s %s", prefix, RX_TAG)
+				if latency, ok := latencies[msg]; ok {
+					prefix = fmt.Sprintf("%s (+%s)", prefix, formatLatency(latency))
+				}
 			}
-		case float64:
-			arr = append(arr, fmt.Sprintf("%s %f", prefix, msg.Content.(float64)))
-		default:
-			log.Fatalf("Unknown msg type: %v\n", t)
+		}
+		arr = append(arr, fmt.Sprintf("%s %s", prefix, text))
+		if opts.PrintInHex {
+			arr = append(arr, toHexLines(msg.Data)...)
 		}
 		i++
 	}
-	return arr[:]
+	return arr[:], matchedRows
 }
 
-func ListToSliceFloat(l *list.List, maxLen int) []float64 {
-	arr := make([]float64, maxLen)
-	i := maxLen - 1
-	for e := l.Front(); e != nil && i >= 0; e = e.Next() {
+// responseLatencies returns the time from each sent message to the first
+// message received after it. The rows are rendered newest first, so this is
+// done in a separate pass from the oldest message.
+func responseLatencies(l *list.List) map[*Message]time.Duration {
+	latencies := make(map[*Message]time.Duration)
+	var lastTx *Message
+	for e := l.Front(); e != nil; e = e.Next() {
 		msg := e.Value.(*Message)
-		switch t := msg.Content.(type) {
-		case float64:
-			arr[i] = msg.Content.(float64)
-		case string:
-			num, err := strconv.ParseFloat(strings.TrimRight(msg.Content.(string), "\r\n"), 64)
-			if err == nil {
-				arr[i] = num
-			} else {
-				i++
+		if msg.Direction == TX {
+			lastTx = msg
+		} else if lastTx != nil {
+			latencies[msg] = msg.Timestamp.Sub(lastTx.Timestamp)
+			lastTx = nil
+		}
+	}
+	return latencies
+}
+
+func messageSpans(msg *Message, opts MsgRenderOptions) []StyledSpan {
+	text := MessageText(msg, opts.Encoding)
+	if opts.RenderAnsi {
+		return ParseAnsi(text)
+	}
+	return []StyledSpan{{Text: EscapeAnsi(text)}}
+}
+
+func spansText(spans []StyledSpan) string {
+	var builder strings.Builder
+	for _, span := range spans {
+		builder.WriteString(span.Text)
+	}
+	return builder.String()
+}
+
+// renderSpans joins the spans in termui style syntax. Spans without own style
+// get the default style and the matches (byte ranges of the joined text) are
+// highlighted, as termui styles cannot be nested.
+func renderSpans(spans []StyledSpan, defaultStyle string, matches [][]int) string {
+	var builder strings.Builder
+	offset := 0
+	for _, span := range spans {
+		style := span.Style
+		if style == "" {
+			style = defaultStyle
+		}
+		start, end := offset, offset+len(span.Text)
+		pos := start
+		for _, match := range matches {
+			from, to := max(match[0], start), min(match[1], end)
+			if from >= to {
+				continue
 			}
-		default:
-			log.Fatalf("Unknown msg type: %v\n", t)
+			builder.WriteString(styleText(span.Text[pos-start:from-start], style))
+			builder.WriteString(styleText(span.Text[from-start:to-start], HIGHLIGHT_STYLE))
+			pos = to
 		}
-		i--
+		builder.WriteString(styleText(span.Text[pos-start:], style))
+		offset = end
+	}
+	return builder.String()
+}
+
+func styleText(text string, style string) string {
+	if text == "" || style == "" {
+		return text
+	}
+	return fmt.Sprintf("[%s](%s)", text, style)
+}
+
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d.Microseconds())/1000.0)
+}
+
+// MessageText renders message bytes in the given encoding. Trailing line
+// endings are dropped, unless control characters are to be shown (ASCII).
+func MessageText(msg *Message, encoding string) string {
+	if encoding == ASCII {
+		return DecodeText(msg.Data, encoding)
 	}
-	return arr[i+1:]
+	return strings.TrimRight(DecodeText(msg.Data, encoding), "\r\n")
 }
 
-func toHexLines(s string) []string {
-	hexString := strings.ToUpper(hex.EncodeToString([]byte(s)))
+func toHexLines(data []byte) []string {
+	hexString := strings.ToUpper(hex.EncodeToString(data))
 	linesCount := int(math.Ceil(float64(len(hexString)) / 16.0))
 	hexLines := make([]string, linesCount)
 	for i := 0; i < linesCount; i++ {
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"container/list"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListToSliceMsgDirection(t *testing.T) {
+	start := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	messages := list.New()
+	for i, m := range []struct {
+		direction Direction
+		data      string
+	}{{TX, "AT"}, {RX, "OK"}} {
+		msg := NewMessage(m.direction, "port", []byte(m.data))
+		msg.Timestamp = start.Add(time.Duration(i) * 5 * time.Millisecond)
+		messages.PushBack(msg)
+	}
+
+	rows, _ := ListToSliceMsg(messages, messages.Len(), MsgRenderOptions{})
+	if want := []string{"[1]: OK"}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows without direction = %q, want %q", rows, want)
+	}
+
+	rows, _ = ListToSliceMsg(messages, messages.Len(), MsgRenderOptions{ShowDirection: true})
+	if len(rows) != 2 {
+		t.Fatalf("rows with direction = %q, want 2 rows", rows)
+	}
+	if !strings.HasPrefix(rows[0], "[1]: "+RX_TAG+" (+") || !strings.HasSuffix(rows[0], "OK") {
+		t.Errorf("received row = %q, want it tagged with the latency", rows[0])
+	}
+	if rows[1] != "[2]: "+TX_TAG+" AT" {
+		t.Errorf("sent row = %q, want it tagged", rows[1])
+	}
+}
+
+func TestListToSliceMsgLatencyOfResponse(t *testing.T) {
+	start := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	messages := list.New()
+	for _, m := range []struct {
+		direction Direction
+		data      string
+		offset    time.Duration
+	}{{RX, "boot", 0}, {TX, "AT", time.Second}, {RX, "OK", time.Second + 5*time.Millisecond}, {RX, "idle", 2 * time.Second}} {
+		msg := NewMessage(m.direction, "port", []byte(m.data))
+		msg.Timestamp = start.Add(m.offset)
+		messages.PushBack(msg)
+	}
+	rows, _ := ListToSliceMsg(messages, messages.Len(), MsgRenderOptions{ShowDirection: true})
+	want := []string{
+		"[1]: " + RX_TAG + " idle",
+		"[2]: " + RX_TAG + " (+5.0ms) OK",
+		"[3]: " + TX_TAG + " AT",
+		"[4]: " + RX_TAG + " boot",
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+}